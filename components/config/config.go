@@ -28,6 +28,8 @@ type Options struct {
 	TokenExpireDuration      int
 	RetrieveLogRetentionTime int
 	AccessKey                string
+	AccessSecret             string            // 调用其他服务时签名请求的密钥
+	AccessKeys               map[string]string // 允许调用本服务的access key和密钥
 	CasbinFileName           string
 	UploadDir                string
 	DocumentLink             string
@@ -46,6 +48,8 @@ func NewOption(path string) (*Options, error) {
 		TokenExpireDuration:      viper.GetInt("token_expire_duration"),
 		RetrieveLogRetentionTime: viper.GetInt("retrieve_log_retention_time"),
 		AccessKey:                viper.GetString("access_key"),
+		AccessSecret:             viper.GetString("access_secret"),
+		AccessKeys:               viper.GetStringMapString("access_keys"),
 		CasbinFileName:           viper.GetString("casbin_file_name"),
 		UploadDir:                viper.GetString("upload_dir"),
 		DocumentLink:             viper.GetString("document_link"),
@@ -53,6 +57,11 @@ func NewOption(path string) (*Options, error) {
 	}, nil
 }
 
+// GetOptions 获取InitConfig读取的配置
+func GetOptions() *Options {
+	return cfg.Options
+}
+
 func InitConfig(path string) {
 	fs, err := os.Stat(path)
 	if err != nil || fs.IsDir() {
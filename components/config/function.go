@@ -1,9 +1,19 @@
 package config
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type FunctionList struct {
@@ -21,26 +31,184 @@ type FunctionConfig struct {
 	List []FunctionList `json:"functionList"`
 }
 
-// 解析function.json文件
-var functionList = &FunctionConfig{
-	List: []FunctionList{},
+// Function 功能菜单节点，支持任意层级
+type Function struct {
+	Id       string      `json:"id"`
+	Name     string      `json:"name"`
+	Icon     string      `json:"icon,omitempty"`
+	Route    string      `json:"route,omitempty"`
+	Order    int         `json:"order,omitempty"`
+	SubItems []*Function `json:"subItems,omitempty"`
 }
 
+// FunctionTree 功能树
+type FunctionTree struct {
+	List []*Function `json:"functionList"`
+}
+
+// 解析function.json文件
+var (
+	functionList = &FunctionConfig{
+		List: []FunctionList{},
+	}
+	functionTree = &FunctionTree{
+		List: []*Function{},
+	}
+	functionLock sync.RWMutex
+)
+
+// InitFunction 加载function.json，失败时退出进程
 func InitFunction(path string) {
+	if err := InitFunctionE(path); err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// InitFunctionE 加载function.json，文件内容可以是功能数组或{"functionList":[...]}，失败时返回错误
+func InitFunctionE(path string) error {
 	file, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatal("初始化function.json文件错误")
+		return fmt.Errorf("读取function.json文件[%s]失败:%s", path, err.Error())
+	}
+	tree, err := ParseFunction(file)
+	if err != nil {
+		return err
+	}
+	setFunctionTree(tree)
+	return nil
+}
+
+// setFunctionTree 替换功能树，同时生成两级的旧版本功能列表
+func setFunctionTree(tree *FunctionTree) {
+	fc := &FunctionConfig{List: []FunctionList{}}
+	for _, f := range tree.List {
+		item := FunctionList{Id: f.Id, Name: f.Name, SubItems: []SubItems{}}
+		for _, sub := range f.SubItems {
+			item.SubItems = append(item.SubItems, SubItems{Id: sub.Id, Name: sub.Name})
+		}
+		fc.List = append(fc.List, item)
 	}
+	functionLock.Lock()
+	functionTree = tree
+	functionList = fc
+	functionLock.Unlock()
+}
+
+// WatchFunction 监听function.json变更并重新加载，解析或校验失败时保留原配置
+func WatchFunction(ctx context.Context, path string, interval time.Duration) {
+	go NewFileProvider(path, interval).Watch(ctx, func(content []byte) {
+		tree, err := ParseFunction(content)
+		if err != nil {
+			logrus.Errorf("重新加载function.json失败，保留原配置:%s", err.Error())
+			return
+		}
+		setFunctionTree(tree)
+		logrus.Infof("function.json[%s]已重新加载", path)
+	})
+}
 
-	var fl []FunctionList
-	err = json.Unmarshal(file, &fl)
+// ParseFunction 解析功能配置，校验id并按order排序
+func ParseFunction(content []byte) (*FunctionTree, error) {
+	tree := &FunctionTree{}
+	content = bytes.TrimSpace(content)
+	var err error
+	if len(content) > 0 && content[0] == '[' {
+		err = json.Unmarshal(content, &tree.List)
+	} else {
+		err = json.Unmarshal(content, tree)
+	}
 	if err != nil {
-		log.Fatal("读取function.json，解析成对应的结构体值错误")
+		return nil, fmt.Errorf("解析function.json失败:%s", err.Error())
+	}
+	if err := validateFunction(tree.List, map[string]bool{}); err != nil {
+		return nil, err
+	}
+	sortFunction(tree.List)
+	return tree, nil
+}
+
+func validateFunction(list []*Function, ids map[string]bool) error {
+	for _, f := range list {
+		if f == nil {
+			return fmt.Errorf("function.json存在空节点")
+		}
+		if f.Id == "" {
+			return fmt.Errorf("功能[%s]的id为空", f.Name)
+		}
+		if ids[f.Id] {
+			return fmt.Errorf("功能id[%s]重复", f.Id)
+		}
+		ids[f.Id] = true
+		if err := validateFunction(f.SubItems, ids); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	functionList.List = fl
+func sortFunction(list []*Function) {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Order < list[j].Order
+	})
+	for _, f := range list {
+		sortFunction(f.SubItems)
+	}
 }
 
 func GetFunctionList() *FunctionConfig {
+	functionLock.RLock()
+	defer functionLock.RUnlock()
 	return functionList
 }
+
+// GetFunctionTree 获取完整的功能树
+func GetFunctionTree() *FunctionTree {
+	functionLock.RLock()
+	defer functionLock.RUnlock()
+	return functionTree
+}
+
+// FindFunction 根据id查找功能
+func FindFunction(id string) *Function {
+	var find func(list []*Function) *Function
+	find = func(list []*Function) *Function {
+		for _, f := range list {
+			if f.Id == id {
+				return f
+			}
+			if sub := find(f.SubItems); sub != nil {
+				return sub
+			}
+		}
+		return nil
+	}
+	return find(GetFunctionTree().List)
+}
+
+// FunctionAuthorizer 判断当前请求的用户是否可以查看功能
+type FunctionAuthorizer func(c *gin.Context, id string) bool
+
+// FilterFunction 按权限过滤功能树，有可见子节点的父节点同样保留
+func FilterFunction(list []*Function, allow func(id string) bool) []*Function {
+	result := []*Function{}
+	for _, f := range list {
+		sub := FilterFunction(f.SubItems, allow)
+		if !allow(f.Id) && len(sub) == 0 {
+			continue
+		}
+		node := *f
+		node.SubItems = sub
+		result = append(result, &node)
+	}
+	return result
+}
+
+// FunctionTreeHandler 返回当前用户可见的功能树
+func FunctionTreeHandler(authorize FunctionAuthorizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list := FilterFunction(GetFunctionTree().List, func(id string) bool {
+			return authorize(c, id)
+		})
+		c.JSON(http.StatusOK, &FunctionTree{List: list})
+	}
+}
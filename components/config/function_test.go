@@ -0,0 +1,113 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testFunctions = `{"functionList":[
+	{"id":"system","name":"系统管理","order":2,"subItems":[
+		{"id":"user","name":"用户管理","order":2},
+		{"id":"role","name":"角色管理","order":1,"subItems":[{"id":"role.edit","name":"编辑角色"}]}
+	]},
+	{"id":"home","name":"首页","order":1}
+]}`
+
+func TestParseFunction(t *testing.T) {
+	tree, err := ParseFunction([]byte(testFunctions))
+	if err != nil {
+		t.Fatalf("解析失败:%s", err)
+	}
+	if tree.List[0].Id != "home" || tree.List[1].SubItems[0].Id != "role" {
+		t.Errorf("未按order排序:%s,%s", tree.List[0].Id, tree.List[1].SubItems[0].Id)
+	}
+
+	// 兼容旧版本的数组格式
+	tree, err = ParseFunction([]byte(`[{"id":"home","name":"首页"}]`))
+	if err != nil || len(tree.List) != 1 {
+		t.Errorf("数组格式解析失败:%v", err)
+	}
+
+	cases := map[string]string{
+		`[{"id":"a"},{"id":"a"}]`:                   "重复",
+		`[{"id":"a","subItems":[{"id":"a"}]}]`:      "重复",
+		`[{"name":"首页"}]`:                           "id为空",
+		`[{"id":"a","subItems":[null]}]`:            "空节点",
+		`{"functionList":[{"id":"a","order":"1"}]}`: "解析function.json失败",
+	}
+	for content, want := range cases {
+		if _, err := ParseFunction([]byte(content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s:期望错误包含%q，实际%v", content, want, err)
+		}
+	}
+}
+
+func TestInitFunction(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "function.json")
+	os.WriteFile(file, []byte(testFunctions), 0644)
+	InitFunction(file)
+
+	list := GetFunctionList().List
+	if len(list) != 2 || list[1].Id != "system" || len(list[1].SubItems) != 2 || list[1].SubItems[0].Id != "role" {
+		t.Errorf("旧版本功能列表不正确:%+v", list)
+	}
+	if f := FindFunction("role.edit"); f == nil || f.Name != "编辑角色" {
+		t.Errorf("未找到三级功能:%+v", f)
+	}
+
+	// 加载失败时保留原配置
+	os.WriteFile(file, []byte(`[{"id":""}]`), 0644)
+	if err := InitFunctionE(file); err == nil {
+		t.Error("id为空时应返回错误")
+	}
+	if FindFunction("role.edit") == nil {
+		t.Error("加载失败时不应覆盖原配置")
+	}
+}
+
+func TestFilterFunction(t *testing.T) {
+	tree, _ := ParseFunction([]byte(testFunctions))
+	allowed := map[string]bool{"home": true, "role.edit": true}
+	list := FilterFunction(tree.List, func(id string) bool {
+		return allowed[id]
+	})
+	// role.edit可见时保留父节点system和role，user不可见
+	if len(list) != 2 || list[1].Id != "system" || len(list[1].SubItems) != 1 ||
+		list[1].SubItems[0].Id != "role" || len(list[1].SubItems[0].SubItems) != 1 {
+		b, _ := json.Marshal(list)
+		t.Errorf("过滤结果不正确:%s", b)
+	}
+	if len(tree.List[1].SubItems) != 2 {
+		t.Error("过滤不应修改原功能树")
+	}
+}
+
+func TestFunctionTreeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tree, _ := ParseFunction([]byte(testFunctions))
+	setFunctionTree(tree)
+
+	r := gin.New()
+	r.GET("/functions", FunctionTreeHandler(func(c *gin.Context, id string) bool {
+		return c.Query("role") == "admin" || id == "home"
+	}))
+	cases := map[string]int{"/functions": 1, "/functions?role=admin": 2}
+	for url, want := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		got := &FunctionTree{}
+		if err := json.Unmarshal(w.Body.Bytes(), got); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s:请求失败%d %s", url, w.Code, w.Body.String())
+		}
+		if len(got.List) != want {
+			t.Errorf("%s:期望%d个功能，实际%d", url, want, len(got.List))
+		}
+	}
+}
@@ -1,16 +1,33 @@
 package config
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/hoorayui/core-framework/types"
 	"github.com/hoorayui/core-framework/util"
+	"github.com/sirupsen/logrus"
 )
 
 type Instance struct {
 	config     types.CfgConfig
 	cfg        types.Config
+	base       types.Config           // 本地配置，远程配置在此基础上覆盖
+	remote     []byte                 // 最近一次的远程配置内容
+	format     string                 // 远程配置格式
+	overrides  map[string]interface{} // 运行时覆盖的配置项，本地或远程配置更新后重新覆盖
 	configFile string
+	l          sync.RWMutex
+	provider   Provider
+	cancel     context.CancelFunc
+	listeners  []func(old, new types.Config)
 }
 
 var instance *Instance
@@ -20,13 +37,220 @@ func (i *Instance) GetName() string {
 }
 func (i *Instance) Init(config interface{}) error {
 	instance = i
-	bytes, _ := json.Marshal(config)
-	json.Unmarshal(bytes, &instance.config)
+	b, _ := json.Marshal(config)
+	json.Unmarshal(b, &instance.config)
 	i.Validate()
-	util.MustLoadConfig(instance.config.Path, &instance.cfg)
+	if i.config.Path != "" {
+		util.MustLoadConfig(instance.config.Path, &instance.cfg)
+	}
+	i.base = copyConfig(i.cfg)
+	remote := mergeCfgConfig(i.config, i.cfg.Config)
+	if remote.Provider == "" {
+		return nil
+	}
+	return i.initProvider(remote)
+}
+
+// initProvider 从远程配置源加载配置并监听变更
+func (i *Instance) initProvider(c types.CfgConfig) error {
+	p, err := NewProvider(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	content, err := fetchWithCache(ctx, p, c.CacheFile)
+	if err != nil {
+		cancel()
+		return err
+	}
+	format := c.Format
+	if format == "" {
+		format = endpointFormat(c.Endpoint)
+	}
+	cfg, err := i.decode(format, content)
+	if err != nil {
+		cancel()
+		return err
+	}
+	i.l.Lock()
+	i.cfg = cfg
+	i.remote = content
+	i.format = format
+	i.provider = p
+	i.cancel = cancel
+	i.l.Unlock()
+	logrus.Infof("配置源[%s]加载成功", p.Name())
+
+	last := content
+	go p.Watch(ctx, func(content []byte) {
+		if bytes.Equal(content, last) {
+			return
+		}
+		last = content
+		cfg, err := i.decode(format, content)
+		if err != nil {
+			logrus.Errorf("配置源[%s]内容解析失败，忽略本次变更:%s", p.Name(), err.Error())
+			return
+		}
+		if c.CacheFile != "" {
+			if err := writeCache(c.CacheFile, content); err != nil {
+				logrus.Warnf("写入配置缓存[%s]失败:%s", c.CacheFile, err.Error())
+			}
+		}
+		i.l.Lock()
+		i.remote = content
+		i.l.Unlock()
+		i.apply(cfg, "provider:"+p.Name())
+		logrus.Infof("配置源[%s]配置已更新", p.Name())
+	})
 	return nil
 }
 
+// decode 在本地配置的基础上覆盖远程配置
+func (i *Instance) decode(format string, content []byte) (types.Config, error) {
+	i.l.RLock()
+	cfg := copyConfig(i.base)
+	i.l.RUnlock()
+	err := util.LoadConfigBytes(format, content, &cfg)
+	return cfg, err
+}
+
+// endpointFormat 取配置源地址路径的扩展名，忽略查询参数，没有扩展名时返回空，由内容识别格式
+func endpointFormat(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Scheme != "" && u.Opaque == "" {
+		return path.Ext(u.Path)
+	}
+	return path.Ext(endpoint)
+}
+
+// apply 替换当前配置，重新覆盖运行时覆盖的配置项，记录变更并通知监听者
+func (i *Instance) apply(cfg types.Config, source string) {
+	i.l.Lock()
+	if len(i.overrides) > 0 {
+		merged, err := applyOverrides(cfg, i.overrides)
+		if err != nil {
+			logrus.Warnf("重新覆盖运行时配置失败，覆盖项未生效:%s", err.Error())
+		} else {
+			cfg = merged
+		}
+	}
+	old := i.cfg
+	i.cfg = cfg
+	listeners := append([]func(old, new types.Config){}, i.listeners...)
+	i.l.Unlock()
+	notify(old, cfg, source, listeners)
+}
+
+// notify 记录变更并通知监听者，在锁外调用，监听者可以读取配置
+func notify(old, new types.Config, source string, listeners []func(old, new types.Config)) {
+	audit(old, new, source)
+	for _, listener := range listeners {
+		listener(old, new)
+	}
+}
+
+// Reload 重新加载本地配置文件，在其上覆盖最近一次的远程配置和运行时覆盖的配置项
+func Reload() error {
+	i := instance
+	if i.config.Path == "" {
+		return fmt.Errorf("未配置本地配置文件")
+	}
+	var base types.Config
+	if err := util.LoadConfig(i.config.Path, &base); err != nil {
+		return fmt.Errorf("加载配置文件[%s]失败:%s", i.config.Path, err.Error())
+	}
+	i.l.Lock()
+	i.base = copyConfig(base)
+	remote, format := i.remote, i.format
+	i.l.Unlock()
+	cfg := base
+	if remote != nil {
+		var err error
+		if cfg, err = i.decode(format, remote); err != nil {
+			return err
+		}
+	}
+	i.apply(cfg, "file:"+i.config.Path)
+	logrus.Infof("配置文件[%s]已重新加载", i.config.Path)
+	return nil
+}
+
+// Override 运行时覆盖配置项，key为点分隔的路径，如server.listen_port，本地或远程配置更新后仍然生效
+func Override(key string, value interface{}, source string) error {
+	i := instance
+	i.l.Lock()
+	cfg, err := applyOverrides(i.cfg, map[string]interface{}{key: value})
+	if err != nil {
+		i.l.Unlock()
+		return err
+	}
+	if i.overrides == nil {
+		i.overrides = map[string]interface{}{}
+	}
+	i.overrides[key] = value
+	old := i.cfg
+	i.cfg = cfg
+	listeners := append([]func(old, new types.Config){}, i.listeners...)
+	i.l.Unlock()
+	notify(old, cfg, source, listeners)
+	return nil
+}
+
+// applyOverrides 按点分隔的路径覆盖配置项，路径不存在或值类型不正确时返回错误
+func applyOverrides(c types.Config, overrides map[string]interface{}) (types.Config, error) {
+	m := toMap(c)
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path := strings.Split(key, ".")
+		cur := m
+		for _, k := range path[:len(path)-1] {
+			next, ok := cur[k].(map[string]interface{})
+			if !ok {
+				return c, fmt.Errorf("配置项[%s]不存在", key)
+			}
+			cur = next
+		}
+		if _, ok := cur[path[len(path)-1]]; !ok {
+			return c, fmt.Errorf("配置项[%s]不存在", key)
+		}
+		cur[path[len(path)-1]] = overrides[key]
+	}
+	var cfg types.Config
+	b, _ := json.Marshal(m)
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return c, fmt.Errorf("配置项%v的值不正确:%s", keys, err.Error())
+	}
+	return cfg, nil
+}
+
+// OnChange 注册配置变更回调
+func OnChange(listener func(old, new types.Config)) {
+	instance.l.Lock()
+	defer instance.l.Unlock()
+	instance.listeners = append(instance.listeners, listener)
+}
+
+// mergeCfgConfig 组件参数优先，未设置的项使用配置文件中config节点的值
+func mergeCfgConfig(c, file types.CfgConfig) types.CfgConfig {
+	if c.Provider == "" {
+		path := c.Path
+		c = file
+		c.Path = path
+	}
+	return c
+}
+
+func copyConfig(c types.Config) types.Config {
+	var cfg types.Config
+	b, _ := json.Marshal(c)
+	json.Unmarshal(b, &cfg)
+	return cfg
+}
+
 // Validate 验证配置
 func (i *Instance) Validate() error {
 	return nil
@@ -34,16 +258,21 @@ func (i *Instance) Validate() error {
 
 // GetInstance 获取实例
 func GetInstance() types.Config {
+	instance.l.RLock()
+	defer instance.l.RUnlock()
 	return instance.cfg
 }
 
 // Close 关闭
 func (i *Instance) Close() {
+	if i.cancel != nil {
+		i.cancel()
+	}
 }
 func GetConfigMap() map[string]interface{} {
 	var configMap map[string]interface{}
-	bytes, _ := json.Marshal(instance.cfg)
-	json.Unmarshal(bytes, &configMap)
+	b, _ := json.Marshal(GetInstance())
+	json.Unmarshal(b, &configMap)
 	return configMap
 }
 func GetConfig(key string) interface{} {
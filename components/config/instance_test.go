@@ -0,0 +1,101 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hoorayui/core-framework/types"
+)
+
+func TestOverride(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	os.WriteFile(file, []byte("server:\n  listen_port: 8080\n  gateway_listen_port: 8081\n"), 0644)
+	store := NewMemoryAuditStore(100)
+	SetAuditStore(store)
+	defer SetAuditStore(NewMemoryAuditStore(1000))
+
+	i := &Instance{}
+	if err := i.Init(types.CfgConfig{Path: file}); err != nil {
+		t.Fatal(err)
+	}
+	defer i.Close()
+
+	if err := Override("server.not_exist", 1, "test"); err == nil {
+		t.Error("配置项不存在时应返回错误")
+	}
+	if err := Override("server.listen_port", "abc", "test"); err == nil {
+		t.Error("值类型不正确时应返回错误")
+	}
+
+	// 并发覆盖不同配置项，都应生效
+	var wg sync.WaitGroup
+	for n, key := range []string{"server.listen_port", "redis.redis_db"} {
+		wg.Add(1)
+		go func(key string, value int) {
+			defer wg.Done()
+			if err := Override(key, value, "test"); err != nil {
+				t.Error(err)
+			}
+		}(key, 7070+n)
+	}
+	wg.Wait()
+	if cfg := GetInstance(); cfg.Server.ListenPort != 7070 || cfg.Redis.DB != 7071 {
+		t.Fatalf("覆盖未生效:%d %d", cfg.Server.ListenPort, cfg.Redis.DB)
+	}
+
+	// 重新加载本地配置后覆盖项仍然生效，变更记录来源为本地文件
+	os.WriteFile(file, []byte("server:\n  listen_port: 8088\n  gateway_listen_port: 9091\n"), 0644)
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := GetInstance(); cfg.Server.ListenPort != 7070 || cfg.Server.GatewayListenPort != 9091 {
+		t.Errorf("重新加载后配置不正确:%d %d", cfg.Server.ListenPort, cfg.Server.GatewayListenPort)
+	}
+	records, _ := store.Query(AuditQuery{Source: "file:" + file})
+	if len(records) != 1 || records[0].Key != "server.gateway_listen_port" {
+		t.Errorf("重新加载的变更记录不正确:%+v", records)
+	}
+}
+
+func TestOverrideProvider(t *testing.T) {
+	var port int32 = 8080
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("server:\n  listen_port: " + strconv.Itoa(int(atomic.LoadInt32(&port))) + "\n"))
+	}))
+	defer srv.Close()
+
+	i := &Instance{}
+	err := i.Init(types.CfgConfig{
+		Provider:     "http",
+		Endpoint:     srv.URL + "/app.yaml?env=test",
+		PollInterval: 1,
+	})
+	if err != nil {
+		t.Fatalf("初始化失败:%s", err)
+	}
+	defer i.Close()
+	if err := Override("server.gateway_listen_port", 7071, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan types.Config, 1)
+	OnChange(func(old, new types.Config) {
+		changed <- new
+	})
+	atomic.StoreInt32(&port, 9090)
+	select {
+	case cfg := <-changed:
+		if cfg.Server.ListenPort != 9090 || cfg.Server.GatewayListenPort != 7071 {
+			t.Errorf("远程配置更新后覆盖项未生效:%d %d", cfg.Server.ListenPort, cfg.Server.GatewayListenPort)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到配置变更")
+	}
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hoorayui/core-framework/types"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultPollInterval 默认轮询间隔
+const DefaultPollInterval = 30 * time.Second
+
+// Provider 配置源，负责拉取和监听配置内容
+type Provider interface {
+	Name() string                                           // 配置源名称
+	Fetch(ctx context.Context) ([]byte, error)              // 拉取配置
+	Watch(ctx context.Context, onChange func([]byte)) error // 监听变更，直到ctx结束
+}
+
+// ProviderFactory 根据配置创建配置源
+type ProviderFactory func(c types.CfgConfig) (Provider, error)
+
+var (
+	providers = map[string]ProviderFactory{
+		"http": func(c types.CfgConfig) (Provider, error) {
+			if c.Endpoint == "" {
+				return nil, fmt.Errorf("http配置源地址为空")
+			}
+			p := NewHTTPProvider(c.Endpoint, pollInterval(c))
+			for k, v := range c.Headers {
+				p.Header.Set(k, v)
+			}
+			return p, nil
+		},
+		"file": func(c types.CfgConfig) (Provider, error) {
+			if c.Endpoint == "" {
+				return nil, fmt.Errorf("file配置源路径为空")
+			}
+			return NewFileProvider(c.Endpoint, pollInterval(c)), nil
+		},
+	}
+	providerLock sync.RWMutex
+)
+
+// RegisterProvider 注册配置源，可用于接入consul、etcd等KV存储
+func RegisterProvider(name string, factory ProviderFactory) {
+	providerLock.Lock()
+	defer providerLock.Unlock()
+	providers[name] = factory
+}
+
+// NewProvider 根据配置创建已注册的配置源
+func NewProvider(c types.CfgConfig) (Provider, error) {
+	providerLock.RLock()
+	factory, ok := providers[c.Provider]
+	providerLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的配置源[%s]", c.Provider)
+	}
+	return factory(c)
+}
+
+func pollInterval(c types.CfgConfig) time.Duration {
+	if c.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+	return time.Duration(c.PollInterval) * time.Second
+}
+
+// HTTPProvider 通过http轮询拉取配置，支持ETag
+type HTTPProvider struct {
+	URL      string
+	Header   http.Header
+	Interval time.Duration
+	Client   *http.Client
+
+	l    sync.Mutex
+	etag string
+	last []byte
+}
+
+// NewHTTPProvider 创建http配置源
+func NewHTTPProvider(url string, interval time.Duration) *HTTPProvider {
+	return &HTTPProvider{
+		URL:      url,
+		Header:   http.Header{},
+		Interval: interval,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name 配置源名称
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+// Fetch 拉取配置，服务端返回304时复用上次内容
+func (p *HTTPProvider) Fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range p.Header {
+		req.Header[k] = v
+	}
+	p.l.Lock()
+	defer p.l.Unlock()
+	if p.etag != "" && p.last != nil {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		p.etag = resp.Header.Get("ETag")
+		p.last = body
+		return body, nil
+	case http.StatusNotModified:
+		return p.last, nil
+	default:
+		return nil, fmt.Errorf("拉取配置[%s]失败，状态码:%d", p.URL, resp.StatusCode)
+	}
+}
+
+// Watch 轮询配置，内容变化时回调
+func (p *HTTPProvider) Watch(ctx context.Context, onChange func([]byte)) error {
+	return poll(ctx, p, p.Interval, onChange)
+}
+
+// FileProvider 从本地文件读取配置，适用于挂载的ConfigMap等场景
+type FileProvider struct {
+	Path     string
+	Interval time.Duration
+}
+
+// NewFileProvider 创建文件配置源
+func NewFileProvider(path string, interval time.Duration) *FileProvider {
+	return &FileProvider{
+		Path:     path,
+		Interval: interval,
+	}
+}
+
+// Name 配置源名称
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+// Fetch 读取配置文件
+func (p *FileProvider) Fetch(ctx context.Context) ([]byte, error) {
+	return os.ReadFile(p.Path)
+}
+
+// Watch 轮询配置文件，内容变化时回调
+func (p *FileProvider) Watch(ctx context.Context, onChange func([]byte)) error {
+	return poll(ctx, p, p.Interval, onChange)
+}
+
+// poll 定时拉取配置，与上次内容不同时回调，首次拉取成功即回调
+func poll(ctx context.Context, p Provider, interval time.Duration, onChange func([]byte)) error {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	var last []byte
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			content, err := p.Fetch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logrus.Warnf("配置源[%s]拉取失败:%s", p.Name(), err.Error())
+				}
+				continue
+			}
+			if last != nil && bytes.Equal(content, last) {
+				continue
+			}
+			last = content
+			onChange(content)
+		}
+	}
+}
+
+// fetchWithCache 拉取配置并写入本地缓存，配置源不可用时读取缓存
+func fetchWithCache(ctx context.Context, p Provider, cacheFile string) ([]byte, error) {
+	content, err := p.Fetch(ctx)
+	if err == nil {
+		if cacheFile != "" {
+			if err := writeCache(cacheFile, content); err != nil {
+				logrus.Warnf("写入配置缓存[%s]失败:%s", cacheFile, err.Error())
+			}
+		}
+		return content, nil
+	}
+	if cacheFile == "" {
+		return nil, err
+	}
+	cached, cacheErr := os.ReadFile(cacheFile)
+	if cacheErr != nil {
+		return nil, fmt.Errorf("配置源[%s]不可用:%s，读取缓存失败:%s", p.Name(), err.Error(), cacheErr.Error())
+	}
+	logrus.Warnf("配置源[%s]不可用，使用本地缓存[%s]:%s", p.Name(), cacheFile, err.Error())
+	return cached, nil
+}
+
+// writeCache 先写临时文件再重命名，避免缓存文件写一半，配置中可能有密钥，只允许当前用户读写
+func writeCache(file string, content []byte) error {
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
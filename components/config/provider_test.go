@@ -0,0 +1,139 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hoorayui/core-framework/types"
+)
+
+func TestHTTPProviderFetch(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("server:\n  listen_port: 8080\n"))
+	}))
+	defer srv.Close()
+
+	p := NewHTTPProvider(srv.URL, time.Second)
+	first, err := p.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("拉取配置失败:%s", err)
+	}
+	second, err := p.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("拉取配置失败:%s", err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("304时应返回上次内容，got:%q", second)
+	}
+	if hits != 2 {
+		t.Errorf("请求次数:%d", hits)
+	}
+}
+
+func TestFetchWithCache(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("server:\n  listen_port: 8080\n"))
+	}))
+	cacheFile := filepath.Join(t.TempDir(), "config.cache")
+	p := NewHTTPProvider(srv.URL, time.Second)
+	if _, err := fetchWithCache(context.Background(), p, cacheFile); err != nil {
+		t.Fatalf("拉取配置失败:%s", err)
+	}
+	info, err := os.Stat(cacheFile)
+	if err != nil {
+		t.Fatalf("缓存文件未写入:%s", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("缓存文件权限:%v", info.Mode().Perm())
+	}
+	srv.Close()
+
+	content, err := fetchWithCache(context.Background(), p, cacheFile)
+	if err != nil {
+		t.Fatalf("配置源不可用时应读取缓存:%s", err)
+	}
+	if string(content) != "server:\n  listen_port: 8080\n" {
+		t.Errorf("缓存内容不正确:%q", content)
+	}
+}
+
+func TestEndpointFormat(t *testing.T) {
+	cases := map[string]string{
+		"http://config.local/app.yaml":              ".yaml",
+		"http://config.local/app.json?env=prod":     ".json",
+		"https://config.local/v1/config?name=a.ini": "",
+		"http://config.local/config":                "",
+		"/etc/app/config.toml":                      ".toml",
+		"conf/app.env":                              ".env",
+	}
+	for endpoint, want := range cases {
+		if got := endpointFormat(endpoint); got != want {
+			t.Errorf("%s:期望%q，实际%q", endpoint, want, got)
+		}
+	}
+}
+
+func TestInstanceWatch(t *testing.T) {
+	var port int32 = 8080
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("server:\n  listen_port: " + strconv.Itoa(int(atomic.LoadInt32(&port))) + "\n"))
+	}))
+	defer srv.Close()
+
+	i := &Instance{}
+	err := i.Init(types.CfgConfig{
+		Provider:     "http",
+		Endpoint:     srv.URL,
+		Format:       "yaml",
+		PollInterval: 1,
+	})
+	if err != nil {
+		t.Fatalf("初始化失败:%s", err)
+	}
+	defer i.Close()
+	if GetInstance().Server.ListenPort != 8080 {
+		t.Fatalf("端口:%d", GetInstance().Server.ListenPort)
+	}
+
+	changed := make(chan int, 1)
+	OnChange(func(old, new types.Config) {
+		changed <- new.Server.ListenPort
+	})
+	atomic.StoreInt32(&port, 9090)
+	select {
+	case got := <-changed:
+		if got != 9090 {
+			t.Errorf("变更后端口:%d", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到配置变更")
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	os.WriteFile(file, []byte("a: 1"), 0644)
+	p, err := NewProvider(types.CfgConfig{Provider: "file", Endpoint: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := p.Fetch(context.Background())
+	if err != nil || string(content) != "a: 1" {
+		t.Errorf("读取配置失败:%q %v", content, err)
+	}
+}
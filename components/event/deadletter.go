@@ -0,0 +1,313 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/go-redis/redis"
+	"github.com/hoorayui/core-framework/util"
+	"gorm.io/gorm"
+)
+
+// 死信存储类型
+const (
+	DeadLetterMysql = "mysql"
+	DeadLetterRedis = "redis"
+)
+
+// ErrDeadLetterNotFound 死信不存在
+var ErrDeadLetterNotFound = errors.New("死信不存在")
+
+// processID 当前进程的标识，用于记录未命名的订阅者
+var processID = util.NewUUIDString("")
+
+// DeadLetter 重试耗尽的事件
+type DeadLetter struct {
+	ID         string    `json:"id" gorm:"primaryKey;size:64"`
+	EventID    string    `json:"event_id" gorm:"size:64;index"`
+	Topic      string    `json:"topic" gorm:"size:255;index"`
+	Key        string    `json:"key" gorm:"size:255"`
+	Group      string    `json:"group" gorm:"size:255"`
+	Subscriber string    `json:"subscriber" gorm:"size:255"` // 订阅者名称，未命名时为进程标识#订阅者编号
+	Payload    string    `json:"payload" gorm:"type:text"`
+	Error      string    `json:"error" gorm:"type:text"`
+	Attempts   int       `json:"attempts"`
+	EventTime  time.Time `json:"event_time"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 表名
+func (DeadLetter) TableName() string {
+	return "event_dead_letter"
+}
+
+func newDeadLetter(e *Event, sub *Subscription, err error, attempts int) *DeadLetter {
+	payload, _ := json.Marshal(e.Payload)
+	return &DeadLetter{
+		ID:         util.NewUUIDString(""),
+		EventID:    e.ID,
+		Topic:      e.Topic,
+		Key:        e.Key,
+		Group:      sub.group,
+		Subscriber: sub.identity(),
+		Payload:    string(payload),
+		Error:      err.Error(),
+		Attempts:   attempts,
+		EventTime:  e.Time,
+		CreatedAt:  util.Now(),
+	}
+}
+
+// DeadLetterStore 死信存储
+type DeadLetterStore interface {
+	Save(d *DeadLetter) error                                    // 保存，ID相同时覆盖
+	List(topic string, offset, limit int) ([]*DeadLetter, error) // 按时间倒序查询
+	Get(id string) (*DeadLetter, error)
+	Delete(id string) error
+}
+
+// SetDeadLetterStore 设置死信存储
+func (i *Instance) SetDeadLetterStore(store DeadLetterStore) {
+	i.deadLetter = store
+}
+
+// DeadLetters 死信存储，未配置时为nil
+func (i *Instance) DeadLetters() DeadLetterStore {
+	return i.deadLetter
+}
+
+// Replay 重新投递死信给处理失败的订阅者，成功后删除死信，失败时更新死信的错误信息。
+// 未命名的订阅者只能在原进程中重放
+func (i *Instance) Replay(ctx context.Context, id string) error {
+	if i.deadLetter == nil {
+		return errors.New("未配置死信存储")
+	}
+	d, err := i.deadLetter.Get(id)
+	if err != nil {
+		return err
+	}
+	e := &Event{
+		ID:      d.EventID,
+		Topic:   d.Topic,
+		Key:     d.Key,
+		Payload: json.RawMessage(d.Payload),
+		Time:    d.EventTime,
+	}
+	var subs []*Subscription
+	for _, sub := range i.match(d.Topic) {
+		if sub.group == d.Group && sub.identity() == d.Subscriber {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) == 0 {
+		return fmt.Errorf("事件[%s]没有可重放的订阅者[%s]，未命名的订阅者只能在原进程中重放，可通过WithName设置名称", d.Topic, d.Subscriber)
+	}
+	var errs []error
+	for _, sub := range subs {
+		if err := i.consumeChain(sub.handler)(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		d.Attempts++
+		d.Error = err.Error()
+		i.deadLetter.Save(d)
+		return err
+	}
+	return i.deadLetter.Delete(id)
+}
+
+// GormDeadLetterStore 数据库死信存储
+type GormDeadLetterStore struct {
+	db *gorm.DB
+}
+
+// NewGormDeadLetterStore 创建数据库死信存储并自动建表
+func NewGormDeadLetterStore(db *gorm.DB) (*GormDeadLetterStore, error) {
+	if err := db.AutoMigrate(&DeadLetter{}); err != nil {
+		return nil, err
+	}
+	return &GormDeadLetterStore{db: db}, nil
+}
+
+// Save 保存死信
+func (s *GormDeadLetterStore) Save(d *DeadLetter) error {
+	return s.db.Save(d).Error
+}
+
+// List 查询死信
+func (s *GormDeadLetterStore) List(topic string, offset, limit int) ([]*DeadLetter, error) {
+	tx := s.db.Model(&DeadLetter{})
+	if topic != "" {
+		tx = tx.Where("topic = ?", topic)
+	}
+	list := []*DeadLetter{}
+	err := tx.Order("created_at DESC").Offset(offset).Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// Get 获取死信
+func (s *GormDeadLetterStore) Get(id string) (*DeadLetter, error) {
+	d := &DeadLetter{}
+	err := s.db.Where("id = ?", id).First(d).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeadLetterNotFound
+	}
+	return d, err
+}
+
+// Delete 删除死信
+func (s *GormDeadLetterStore) Delete(id string) error {
+	return s.db.Where("id = ?", id).Delete(&DeadLetter{}).Error
+}
+
+// RedisDeadLetterStore redis死信存储，hash保存内容，有序集合按时间排序
+type RedisDeadLetterStore struct {
+	client *goredis.Client
+	prefix string
+}
+
+// NewRedisDeadLetterStore 创建redis死信存储
+func NewRedisDeadLetterStore(client *goredis.Client, prefix string) *RedisDeadLetterStore {
+	return &RedisDeadLetterStore{
+		client: client,
+		prefix: prefix + "dead_letter",
+	}
+}
+
+func (s *RedisDeadLetterStore) indexKey(topic string) string {
+	if topic == "" {
+		return s.prefix + ":index"
+	}
+	return s.prefix + ":index:" + topic
+}
+
+// Save 保存死信
+func (s *RedisDeadLetterStore) Save(d *DeadLetter) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	score := float64(d.CreatedAt.UnixNano())
+	pipe := s.client.TxPipeline()
+	pipe.HSet(s.prefix, d.ID, string(b))
+	pipe.ZAdd(s.indexKey(""), goredis.Z{Score: score, Member: d.ID})
+	pipe.ZAdd(s.indexKey(d.Topic), goredis.Z{Score: score, Member: d.ID})
+	_, err = pipe.Exec()
+	return err
+}
+
+// List 查询死信
+func (s *RedisDeadLetterStore) List(topic string, offset, limit int) ([]*DeadLetter, error) {
+	ids, err := s.client.ZRevRange(s.indexKey(topic), int64(offset), int64(offset+limit-1)).Result()
+	if err != nil || len(ids) == 0 {
+		return []*DeadLetter{}, err
+	}
+	values, err := s.client.HMGet(s.prefix, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*DeadLetter, 0, len(values))
+	for _, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			continue
+		}
+		d := &DeadLetter{}
+		if err := json.Unmarshal([]byte(raw), d); err == nil {
+			list = append(list, d)
+		}
+	}
+	return list, nil
+}
+
+// Get 获取死信
+func (s *RedisDeadLetterStore) Get(id string) (*DeadLetter, error) {
+	raw, err := s.client.HGet(s.prefix, id).Result()
+	if err == goredis.Nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	d := &DeadLetter{}
+	return d, json.Unmarshal([]byte(raw), d)
+}
+
+// Delete 删除死信
+func (s *RedisDeadLetterStore) Delete(id string) error {
+	d, err := s.Get(id)
+	if err == ErrDeadLetterNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	pipe := s.client.TxPipeline()
+	pipe.HDel(s.prefix, id)
+	pipe.ZRem(s.indexKey(""), id)
+	pipe.ZRem(s.indexKey(d.Topic), id)
+	_, err = pipe.Exec()
+	return err
+}
+
+// DeadLetterListHandler 查询死信，支持topic、offset、limit参数
+func DeadLetterListHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := GetInstance().DeadLetters()
+		if store == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "未配置死信存储"})
+			return
+		}
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if offset < 0 || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset或limit参数不正确"})
+			return
+		}
+		list, err := store.List(c.Query("topic"), offset, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// DeadLetterReplayHandler 重放死信，路由需包含:id参数
+func DeadLetterReplayHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := GetInstance().Replay(c.Request.Context(), c.Param("id"))
+		if errors.Is(err, ErrDeadLetterNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	}
+}
+
+// DeadLetterDiscardHandler 丢弃死信，路由需包含:id参数
+func DeadLetterDiscardHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := GetInstance().DeadLetters()
+		if store == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "未配置死信存储"})
+			return
+		}
+		if err := store.Delete(c.Param("id")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	}
+}
@@ -0,0 +1,167 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hoorayui/core-framework/components/log"
+)
+
+// 队列满时的处理策略
+const (
+	BackpressureBlock = "block" // 阻塞直到队列有空位或ctx结束
+	BackpressureDrop  = "drop"  // 丢弃事件
+	BackpressureError = "error" // 返回ErrQueueFull
+)
+
+var (
+	ErrQueueFull = errors.New("事件队列已满")
+	ErrClosed    = errors.New("事件总线已关闭")
+)
+
+type task struct {
+	ctx  context.Context
+	e    *Event
+	subs []*Subscription
+}
+
+// dispatcher 异步分发，相同key的事件由同一个worker按顺序处理
+type dispatcher struct {
+	bus     *Instance
+	queues  []chan *task
+	policy  string
+	next    uint32
+	wg      sync.WaitGroup
+	senders sync.WaitGroup // 正在放入队列的发布方，关闭队列前等待
+	stop    chan struct{}  // 关闭时通知阻塞的发布方
+	l       sync.RWMutex
+	closed  bool
+}
+
+// workerKey worker执行订阅者时ctx中的标记，用于识别订阅者在worker中发布的事件
+type workerKey struct{}
+
+func newDispatcher(bus *Instance, workers, queueSize int, policy string) *dispatcher {
+	d := &dispatcher{
+		bus:    bus,
+		queues: make([]chan *task, workers),
+		policy: policy,
+		stop:   make(chan struct{}),
+	}
+	for idx := range d.queues {
+		d.queues[idx] = make(chan *task, queueSize)
+		d.wg.Add(1)
+		go d.work(d.queues[idx])
+	}
+	return d
+}
+
+func (d *dispatcher) work(queue chan *task) {
+	defer d.wg.Done()
+	for t := range queue {
+		d.bus.deliver(context.WithValue(t.ctx, workerKey{}, d), t.e, t.subs)
+	}
+}
+
+// dispatch 将事件放入队列，ctx为发布方的ctx。
+// 订阅者使用收到的ctx发布事件时在worker中执行，队列满时不阻塞，返回ErrQueueFull，
+// 避免worker互相等待对方的队列导致死锁
+func (d *dispatcher) dispatch(ctx context.Context, t *task) error {
+	d.l.RLock()
+	if d.closed {
+		d.l.RUnlock()
+		return ErrClosed
+	}
+	d.senders.Add(1)
+	d.l.RUnlock()
+	defer d.senders.Done()
+
+	queue := d.queue(t.e.Key)
+	policy := d.policy
+	if policy == BackpressureBlock && ctx.Value(workerKey{}) == d {
+		policy = BackpressureError
+	}
+	switch policy {
+	case BackpressureDrop:
+		select {
+		case queue <- t:
+		default:
+			log.Errorf("事件队列已满，丢弃事件[%s]", t.e.Topic)
+		}
+		return nil
+	case BackpressureError:
+		select {
+		case queue <- t:
+			return nil
+		default:
+			return ErrQueueFull
+		}
+	default:
+		select {
+		case queue <- t:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.stop:
+			return ErrClosed
+		}
+	}
+}
+
+func (d *dispatcher) queue(key string) chan *task {
+	if key == "" {
+		return d.queues[atomic.AddUint32(&d.next, 1)%uint32(len(d.queues))]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return d.queues[h.Sum32()%uint32(len(d.queues))]
+}
+
+// close 停止接收事件，等待队列中的事件处理完成
+func (d *dispatcher) close(timeout time.Duration) {
+	d.l.Lock()
+	if d.closed {
+		d.l.Unlock()
+		return
+	}
+	d.closed = true
+	d.l.Unlock()
+	// 阻塞的发布方返回ErrClosed，所有发布方退出后才能关闭队列
+	close(d.stop)
+	d.senders.Wait()
+	for _, queue := range d.queues {
+		close(queue)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Errorf("等待事件处理完成超时[%s]", timeout)
+	}
+}
+
+// detach 异步处理时不引用发布方的ctx，如gin.Context请求结束后会被复用。
+// 只将request_id复制到新的ctx，元数据复制一份随事件传递
+func detach(ctx context.Context, e *Event) (context.Context, *Event) {
+	detached := context.Background()
+	if id := RequestID(ctx); id != "" {
+		detached = WithRequestID(detached, id)
+	}
+	copied := *e
+	if e.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(e.Metadata))
+		for k, v := range e.Metadata {
+			copied.Metadata[k] = v
+		}
+	}
+	return detached, &copied
+}
@@ -0,0 +1,203 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hoorayui/core-framework/types"
+)
+
+func TestKeyOrdering(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{Async: true, Workers: 4, QueueSize: 16})
+	var l sync.Mutex
+	received := map[string][]int{}
+	bus.Subscribe("order.updated", func(ctx context.Context, e *Event) error {
+		l.Lock()
+		defer l.Unlock()
+		received[e.Key] = append(received[e.Key], e.Payload.(int))
+		return nil
+	})
+	for n := 0; n < 100; n++ {
+		for _, key := range []string{"a", "b", "c"} {
+			if err := bus.Publish(context.Background(), "order.updated", n, WithKey(key)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// 关闭时等待队列中的事件处理完成
+	bus.Close()
+	for _, key := range []string{"a", "b", "c"} {
+		if len(received[key]) != 100 {
+			t.Fatalf("key[%s]收到%d个事件", key, len(received[key]))
+		}
+		for n, v := range received[key] {
+			if v != n {
+				t.Fatalf("key[%s]的事件未按顺序处理:%v", key, received[key])
+			}
+		}
+	}
+}
+
+// blockingBus 单个worker、队列长度为1，订阅者阻塞直到release关闭
+func blockingBus(t *testing.T, policy string) (bus *Instance, handled *int32, release chan struct{}) {
+	bus = newTestBus(t, types.EventConfig{Async: true, Workers: 1, QueueSize: 1, Backpressure: policy})
+	handled = new(int32)
+	release = make(chan struct{})
+	started := make(chan struct{}, 1)
+	bus.Subscribe("order.created", func(ctx context.Context, e *Event) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		atomic.AddInt32(handled, 1)
+		return nil
+	})
+	// 第一个事件由worker取出并阻塞，第二个事件占满队列
+	bus.Publish(context.Background(), "order.created", 1)
+	<-started
+	if err := bus.Publish(context.Background(), "order.created", 2); err != nil {
+		t.Fatal(err)
+	}
+	return bus, handled, release
+}
+
+func TestBackpressure(t *testing.T) {
+	t.Run(BackpressureError, func(t *testing.T) {
+		bus, handled, release := blockingBus(t, BackpressureError)
+		if err := bus.Publish(context.Background(), "order.created", 3); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("队列满时应返回ErrQueueFull:%v", err)
+		}
+		close(release)
+		bus.Close()
+		if *handled != 2 {
+			t.Errorf("处理了%d个事件", *handled)
+		}
+	})
+	t.Run(BackpressureDrop, func(t *testing.T) {
+		bus, handled, release := blockingBus(t, BackpressureDrop)
+		if err := bus.Publish(context.Background(), "order.created", 3); err != nil {
+			t.Errorf("丢弃策略不应返回错误:%v", err)
+		}
+		close(release)
+		bus.Close()
+		if *handled != 2 {
+			t.Errorf("队列满时应丢弃事件，处理了%d个事件", *handled)
+		}
+	})
+	t.Run(BackpressureBlock, func(t *testing.T) {
+		bus, handled, release := blockingBus(t, BackpressureBlock)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := bus.Publish(ctx, "order.created", 3); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("阻塞策略应等待到ctx结束:%v", err)
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- bus.Publish(context.Background(), "order.created", 4)
+		}()
+		close(release)
+		if err := <-done; err != nil {
+			t.Errorf("队列有空位后应发布成功:%v", err)
+		}
+		bus.Close()
+		if *handled != 3 {
+			t.Errorf("处理了%d个事件", *handled)
+		}
+	})
+}
+
+func TestCloseUnblocksPublisher(t *testing.T) {
+	bus, _, release := blockingBus(t, BackpressureBlock)
+	published := make(chan error, 1)
+	go func() {
+		published <- bus.Publish(context.Background(), "order.created", 3)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		bus.Close()
+		close(closed)
+	}()
+	select {
+	case err := <-published:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("关闭时阻塞的发布应返回ErrClosed:%v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("关闭时发布方仍然阻塞")
+	}
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("关闭超时")
+	}
+	if err := bus.Publish(context.Background(), "order.created", 4); !errors.Is(err, ErrClosed) {
+		t.Errorf("关闭后发布应返回ErrClosed:%v", err)
+	}
+}
+
+func TestPublishFromWorker(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{Async: true, Workers: 1, QueueSize: 1})
+	var handled int32
+	bus.Subscribe("order.paid", func(ctx context.Context, e *Event) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+	errs := make(chan error, 2)
+	bus.Subscribe("order.created", func(ctx context.Context, e *Event) error {
+		// 唯一的worker正在执行当前订阅者，第二个事件放不进队列
+		for n := 0; n < 2; n++ {
+			errs <- bus.Publish(ctx, "order.paid", n)
+		}
+		return nil
+	})
+	bus.Publish(context.Background(), "order.created", nil)
+
+	for n := 0; n < 2; n++ {
+		select {
+		case err := <-errs:
+			if want := []error{nil, ErrQueueFull}[n]; !errors.Is(err, want) {
+				t.Errorf("第%d次发布:期望%v，实际%v", n+1, want, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("worker中发布事件死锁")
+		}
+	}
+	bus.Close()
+	if handled != 1 {
+		t.Errorf("处理了%d个事件", handled)
+	}
+}
+
+func TestDetachContext(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{Async: true, Workers: 1, QueueSize: 1})
+	type callerKey struct{}
+	got := make(chan context.Context, 1)
+	bus.Subscribe("order.created", func(ctx context.Context, e *Event) error {
+		got <- ctx
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.WithValue(WithRequestID(context.Background(), "req-1"), callerKey{}, "gin"))
+	if err := bus.Publish(ctx, "order.created", 1); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case c := <-got:
+		if RequestID(c) != "req-1" {
+			t.Errorf("应复制request_id:%s", RequestID(c))
+		}
+		if c.Value(callerKey{}) != nil || c.Err() != nil {
+			t.Error("异步处理不应引用发布方的ctx")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("事件未处理")
+	}
+}
@@ -2,19 +2,48 @@ package event
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
 	"sync"
+	"time"
 
+	goredis "github.com/go-redis/redis"
+	"github.com/hoorayui/core-framework/components/mysql"
+	"github.com/hoorayui/core-framework/components/redis"
 	"github.com/hoorayui/core-framework/types"
+	"github.com/hoorayui/core-framework/util"
 )
 
 type Instance struct {
-	config  types.RedisConfig
-	watcher map[string][]func(string, interface{}) error
-    l sync.RWMutex
+	config     types.EventConfig
+	watcher    map[string][]*Subscription
+	wildcards  map[string]bool
+	l          sync.RWMutex
+	nextID     uint64
+	dispatcher *dispatcher
+	transport  Transport
+	remoteRefs map[string]int
+	deadLetter DeadLetterStore
+	client     *goredis.Client
+	outbox     *OutboxRelay
+	eventLog   EventLog
+	logStop    chan struct{}
+	requests   *requester
+
+	publishInterceptors []PublishInterceptor
+	consumeInterceptors []ConsumeInterceptor
 }
 
 var instance *Instance
 
+func init() {
+	instance = &Instance{
+		watcher:   map[string][]*Subscription{},
+		wildcards: map[string]bool{},
+	}
+}
+
 // GetName 组件名称
 func (i *Instance) GetName() string {
 	return "event"
@@ -22,17 +51,232 @@ func (i *Instance) GetName() string {
 
 // Init 初始化实例
 func (i *Instance) Init(config interface{}) error {
-	instance = i
-    i.l = sync.RWMutex{}
 	bytes, _ := json.Marshal(config)
 	json.Unmarshal(bytes, &i.config)
-	i.Validate()
-	//TODO
+	if err := i.Validate(); err != nil {
+		return err
+	}
+	if i.config.Transport != "" {
+		transport, err := i.newTransport()
+		if err != nil {
+			return err
+		}
+		i.transport = transport
+	}
+	if err := i.initDeadLetter(); err != nil {
+		return err
+	}
+	if err := i.initOutbox(); err != nil {
+		return err
+	}
+	if err := i.initEventLog(); err != nil {
+		return err
+	}
+	// 保留组件加载前注册的订阅和拦截器，传输层不支持的订阅返回错误
+	var errs []error
+	i.l.Lock()
+	if i.watcher == nil {
+		i.watcher = map[string][]*Subscription{}
+	}
+	i.remoteRefs = map[string]int{}
+	i.wildcards = map[string]bool{}
+	if instance != nil && instance != i {
+		instance.l.RLock()
+		i.nextID = instance.nextID
+		i.requests = instance.requests
+		i.publishInterceptors = append(append([]PublishInterceptor{}, instance.publishInterceptors...), i.publishInterceptors...)
+		i.consumeInterceptors = append(append([]ConsumeInterceptor{}, instance.consumeInterceptors...), i.consumeInterceptors...)
+		for topic, subs := range instance.watcher {
+			for _, sub := range subs {
+				sub.bus = i
+				if err := i.remoteSubscribe(sub); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			i.watcher[topic] = append(i.watcher[topic], subs...)
+			if IsWildcard(topic) {
+				i.wildcards[topic] = true
+			}
+		}
+		instance.l.RUnlock()
+	}
+	i.l.Unlock()
+	if err := errors.Join(errs...); err != nil {
+		if i.transport != nil {
+			i.transport.Close()
+		}
+		return err
+	}
+	if i.config.Async {
+		i.dispatcher = newDispatcher(i, i.config.Workers, i.config.QueueSize, i.config.Backpressure)
+	}
+	instance = i
+	if i.outbox != nil {
+		i.outbox.start()
+	}
+	if i.eventLog != nil && i.config.EventLogRetention > 0 {
+		i.logStop = make(chan struct{})
+		go i.retainLog(time.Duration(i.config.EventLogRetention)*24*time.Hour, i.logStop)
+	}
+	return nil
+}
+
+// redisClient 未配置redis地址时使用redis组件的连接
+func (i *Instance) redisClient() (*goredis.Client, error) {
+	if i.client != nil {
+		return i.client, nil
+	}
+	if i.config.Addr != "" {
+		client := goredis.NewClient(&goredis.Options{
+			Addr:     i.config.Addr,
+			Password: i.config.Password,
+			DB:       i.config.DB,
+		})
+		if err := client.Ping().Err(); err != nil {
+			return nil, err
+		}
+		i.client = client
+	} else if redis.GetInstance() != nil {
+		i.client = redis.GetInstance().Client()
+	} else {
+		return nil, errors.New("事件组件未配置redis地址")
+	}
+	return i.client, nil
+}
+
+// newTransport 创建传输层
+func (i *Instance) newTransport() (Transport, error) {
+	client, err := i.redisClient()
+	if err != nil {
+		return nil, err
+	}
+	if i.config.Transport == TransportRedisPubSub {
+		return NewRedisPubSubTransport(client, i.config.StreamPrefix, i.receive), nil
+	}
+	return NewRedisStreamTransport(client, i.config.StreamPrefix, i.config.Consumer, i.config.StreamMaxLen, i.receive), nil
+}
+
+// initDeadLetter 创建死信存储
+func (i *Instance) initDeadLetter() error {
+	switch i.config.DeadLetter {
+	case DeadLetterMysql:
+		if mysql.GetInstance() == nil || mysql.GetInstance().Client() == nil {
+			return errors.New("死信存储需要先加载mysql组件")
+		}
+		store, err := NewGormDeadLetterStore(mysql.GetInstance().Client())
+		if err != nil {
+			return err
+		}
+		i.deadLetter = store
+	case DeadLetterRedis:
+		client, err := i.redisClient()
+		if err != nil {
+			return err
+		}
+		i.deadLetter = NewRedisDeadLetterStore(client, i.config.StreamPrefix)
+	}
+	return nil
+}
+
+// initOutbox 创建发件箱投递器
+func (i *Instance) initOutbox() error {
+	if !i.config.Outbox {
+		return nil
+	}
+	if mysql.GetInstance() == nil || mysql.GetInstance().Client() == nil {
+		return errors.New("事务发件箱需要先加载mysql组件")
+	}
+	relay, err := newOutboxRelay(mysql.GetInstance().Client(), i)
+	if err != nil {
+		return err
+	}
+	relay.Interval = time.Duration(i.config.OutboxInterval) * time.Millisecond
+	relay.BatchSize = i.config.OutboxBatchSize
+	relay.Retention = time.Duration(i.config.OutboxRetention) * 24 * time.Hour
+	relay.MaxAttempts = i.config.OutboxMaxAttempts
+	i.outbox = relay
+	return nil
+}
+
+// initEventLog 创建事件日志
+func (i *Instance) initEventLog() error {
+	switch i.config.EventLog {
+	case EventLogMysql:
+		if mysql.GetInstance() == nil || mysql.GetInstance().Client() == nil {
+			return errors.New("事件日志需要先加载mysql组件")
+		}
+		store, err := NewGormEventLog(mysql.GetInstance().Client())
+		if err != nil {
+			return err
+		}
+		i.eventLog = store
+	case EventLogFile:
+		store, err := NewFileEventLog(i.config.EventLogDir, i.config.EventLogSegmentSize<<20)
+		if err != nil {
+			return err
+		}
+		i.eventLog = store
+	}
 	return nil
 }
 
 // Validate 验证配置
 func (i *Instance) Validate() error {
+	if i.config.Workers <= 0 {
+		i.config.Workers = runtime.NumCPU()
+	}
+	if i.config.QueueSize <= 0 {
+		i.config.QueueSize = 1024
+	}
+	if i.config.CloseTimeout <= 0 {
+		i.config.CloseTimeout = 10
+	}
+	if i.config.OutboxInterval <= 0 {
+		i.config.OutboxInterval = 1000
+	}
+	if i.config.OutboxBatchSize <= 0 {
+		i.config.OutboxBatchSize = 100
+	}
+	if i.config.OutboxRetention <= 0 {
+		i.config.OutboxRetention = 7
+	}
+	if i.config.OutboxMaxAttempts <= 0 {
+		i.config.OutboxMaxAttempts = 10
+	}
+	if i.config.StreamPrefix == "" {
+		i.config.StreamPrefix = DefaultStreamPrefix
+	}
+	if i.config.StreamMaxLen <= 0 {
+		i.config.StreamMaxLen = DefaultStreamMaxLen
+	}
+	switch i.config.Transport {
+	case "", TransportRedisPubSub, TransportRedisStream:
+	default:
+		return fmt.Errorf("不支持的事件传输层[%s]", i.config.Transport)
+	}
+	switch i.config.DeadLetter {
+	case "", DeadLetterMysql, DeadLetterRedis:
+	default:
+		return fmt.Errorf("不支持的死信存储[%s]", i.config.DeadLetter)
+	}
+	switch i.config.EventLog {
+	case "", EventLogMysql, EventLogFile:
+	default:
+		return fmt.Errorf("不支持的事件日志存储[%s]", i.config.EventLog)
+	}
+	if i.config.EventLogDir == "" {
+		i.config.EventLogDir = util.GetAppRoot() + "/../event_log"
+	}
+	if i.config.EventLogSegmentSize <= 0 {
+		i.config.EventLogSegmentSize = 64
+	}
+	switch i.config.Backpressure {
+	case "":
+		i.config.Backpressure = BackpressureBlock
+	case BackpressureBlock, BackpressureDrop, BackpressureError:
+	default:
+		return fmt.Errorf("不支持的事件队列策略[%s]", i.config.Backpressure)
+	}
 	return nil
 }
 
@@ -41,6 +285,22 @@ func GetInstance() *Instance {
 	return instance
 }
 
-// Close 关闭
+// Close 关闭，停止发件箱投递和接收传输层事件并删除响应主题，异步模式下等待队列中的事件处理完成，最后关闭事件日志
 func (i *Instance) Close() {
+	if i.outbox != nil {
+		i.outbox.close()
+	}
+	i.closeReplies()
+	if i.transport != nil {
+		i.transport.Close()
+	}
+	if i.dispatcher != nil {
+		i.dispatcher.close(time.Duration(i.config.CloseTimeout) * time.Second)
+	}
+	if i.logStop != nil {
+		close(i.logStop)
+	}
+	if i.eventLog != nil {
+		i.eventLog.Close()
+	}
 }
@@ -0,0 +1,100 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hoorayui/core-framework/types"
+)
+
+// newTestBus 创建只在进程内分发的实例并替换全局实例，不继承之前测试的订阅者
+func newTestBus(t *testing.T, config types.EventConfig) *Instance {
+	t.Helper()
+	instance = &Instance{
+		watcher:   map[string][]*Subscription{},
+		wildcards: map[string]bool{},
+	}
+	i := &Instance{}
+	if err := i.Init(config); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(i.Close)
+	return i
+}
+
+func TestPublishSync(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	errFailed := errors.New("处理失败")
+	var called int32
+	bus.Subscribe("order.created", func(ctx context.Context, e *Event) error {
+		atomic.AddInt32(&called, 1)
+		return errFailed
+	})
+	bus.Subscribe("order.created", func(ctx context.Context, e *Event) error {
+		atomic.AddInt32(&called, 1)
+		var payload map[string]int
+		if err := e.Decode(&payload); err != nil || payload["id"] != 1 {
+			t.Errorf("payload不正确:%v %v", payload, err)
+		}
+		return nil
+	})
+
+	err := bus.Publish(context.Background(), "order.created", map[string]int{"id": 1})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("同步模式应返回订阅者的错误:%v", err)
+	}
+	if called != 2 {
+		t.Errorf("订阅者出错时其他订阅者仍应执行，实际执行%d次", called)
+	}
+	if err := bus.Publish(context.Background(), "order.paid", nil); err != nil {
+		t.Errorf("没有订阅者不是错误:%s", err)
+	}
+}
+
+func TestSubscribeOnce(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	var called int32
+	sub, _ := bus.SubscribeOnce("order.created", func(ctx context.Context, e *Event) error {
+		atomic.AddInt32(&called, 1)
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bus.Publish(context.Background(), "order.created", nil)
+		}()
+	}
+	wg.Wait()
+	if called != 1 {
+		t.Errorf("并发发布时只应执行一次，实际%d次", called)
+	}
+	if len(bus.match("order.created")) != 0 {
+		t.Error("执行后应自动取消订阅")
+	}
+	sub.Unsubscribe()
+}
+
+func TestUnsubscribe(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	var called int32
+	sub, _ := Subscribe("order.created", func(ctx context.Context, e *Event) error {
+		atomic.AddInt32(&called, 1)
+		return nil
+	})
+	if GetInstance() != bus {
+		t.Fatal("全局实例不正确")
+	}
+	Publish(context.Background(), "order.created", nil)
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	Publish(context.Background(), "order.created", nil)
+	if called != 1 {
+		t.Errorf("取消订阅后不应再执行，实际%d次", called)
+	}
+}
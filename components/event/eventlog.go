@@ -0,0 +1,227 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hoorayui/core-framework/components/log"
+	"gorm.io/gorm"
+)
+
+// 事件日志存储类型
+const (
+	EventLogMysql = "mysql"
+	EventLogFile  = "file"
+)
+
+// MetadataOffset 重放事件时元数据中的日志位置
+const MetadataOffset = "offset"
+
+// ErrEventLogDisabled 未配置事件日志
+var ErrEventLogDisabled = errors.New("未配置事件日志")
+
+// LogRecord 事件日志记录，Offset从1开始递增
+type LogRecord struct {
+	Offset    int64     `json:"offset" gorm:"column:seq;primaryKey;autoIncrement"`
+	ID        string    `json:"id" gorm:"size:64;index"`
+	Topic     string    `json:"topic" gorm:"size:255;index"`
+	Key       string    `json:"key,omitempty" gorm:"size:255"`
+	Payload   string    `json:"payload" gorm:"type:text"`
+	RequestID string    `json:"request_id,omitempty" gorm:"size:64"`
+	Time      time.Time `json:"time" gorm:"column:event_time;index"`
+}
+
+// TableName 表名
+func (LogRecord) TableName() string {
+	return "event_log"
+}
+
+func newLogRecord(e *Event) (*LogRecord, error) {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return &LogRecord{
+		ID:        e.ID,
+		Topic:     e.Topic,
+		Key:       e.Key,
+		Payload:   string(payload),
+		RequestID: e.Metadata[MetadataRequestID],
+		Time:      e.Time,
+	}, nil
+}
+
+func (r *LogRecord) event() *Event {
+	e := &Event{
+		ID:      r.ID,
+		Topic:   r.Topic,
+		Key:     r.Key,
+		Payload: json.RawMessage(r.Payload),
+		Time:    r.Time,
+	}
+	if r.RequestID != "" {
+		e.SetMetadata(MetadataRequestID, r.RequestID)
+	}
+	e.SetMetadata(MetadataOffset, strconv.FormatInt(r.Offset, 10))
+	return e
+}
+
+// EventLog 只追加的事件日志
+type EventLog interface {
+	Append(r *LogRecord) error                        // 追加记录并设置Offset
+	Read(from int64, limit int) ([]*LogRecord, error) // 读取Offset不小于from的记录
+	OffsetAt(t time.Time) (int64, error)              // 第一条时间不早于t的记录的Offset，没有时返回下一条记录的Offset
+	Trim(before time.Time) error                      // 删除早于before的记录，文件存储按分段删除
+	Close() error
+}
+
+// SetEventLog 设置事件日志
+func (i *Instance) SetEventLog(store EventLog) {
+	i.eventLog = store
+}
+
+// EventLog 事件日志，未配置时为nil
+func (i *Instance) EventLog() EventLog {
+	return i.eventLog
+}
+
+// appendLog 记录发布的事件，不记录响应主题
+func (i *Instance) appendLog(ctx context.Context, e *Event) error {
+	if i.eventLog == nil || strings.HasPrefix(e.Topic, ReplyTopicPrefix) {
+		return nil
+	}
+	r, err := newLogRecord(e)
+	if err != nil {
+		return err
+	}
+	if r.RequestID == "" {
+		r.RequestID = RequestID(ctx)
+	}
+	return i.eventLog.Append(r)
+}
+
+// ReplayFromOffset 按顺序重放Offset不小于from的事件，topic为空时重放所有主题，支持通配符。
+// handler返回错误时停止重放，返回值为下一条待重放的Offset，可用于断点续放
+func (i *Instance) ReplayFromOffset(ctx context.Context, topic string, from int64, handler Handler) (int64, error) {
+	if i.eventLog == nil {
+		return from, ErrEventLogDisabled
+	}
+	const batch = 500
+	for {
+		list, err := i.eventLog.Read(from, batch)
+		if err != nil {
+			return from, err
+		}
+		for _, r := range list {
+			if err := ctx.Err(); err != nil {
+				return from, err
+			}
+			if topic == "" || MatchTopic(topic, r.Topic) {
+				e := r.event()
+				hctx := ctx
+				if r.RequestID != "" {
+					hctx = WithRequestID(ctx, r.RequestID)
+				}
+				if err := handler(hctx, e); err != nil {
+					return r.Offset, err
+				}
+			}
+			from = r.Offset + 1
+		}
+		if len(list) < batch {
+			return from, nil
+		}
+	}
+}
+
+// ReplayFromTime 重放时间不早于t的事件
+func (i *Instance) ReplayFromTime(ctx context.Context, topic string, t time.Time, handler Handler) (int64, error) {
+	if i.eventLog == nil {
+		return 0, ErrEventLogDisabled
+	}
+	from, err := i.eventLog.OffsetAt(t)
+	if err != nil {
+		return 0, err
+	}
+	return i.ReplayFromOffset(ctx, topic, from, handler)
+}
+
+// ReplayFromOffset 重放事件日志
+func ReplayFromOffset(ctx context.Context, topic string, from int64, handler Handler) (int64, error) {
+	return GetInstance().ReplayFromOffset(ctx, topic, from, handler)
+}
+
+// ReplayFromTime 重放事件日志
+func ReplayFromTime(ctx context.Context, topic string, t time.Time, handler Handler) (int64, error) {
+	return GetInstance().ReplayFromTime(ctx, topic, t, handler)
+}
+
+// retainLog 定期按保留时间清理事件日志
+func (i *Instance) retainLog(retention time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if err := i.eventLog.Trim(time.Now().Add(-retention)); err != nil {
+			log.Errorf("清理事件日志失败:%s", err.Error())
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GormEventLog 数据库事件日志
+type GormEventLog struct {
+	db *gorm.DB
+}
+
+// NewGormEventLog 创建数据库事件日志并自动建表
+func NewGormEventLog(db *gorm.DB) (*GormEventLog, error) {
+	if err := db.AutoMigrate(&LogRecord{}); err != nil {
+		return nil, err
+	}
+	return &GormEventLog{db: db}, nil
+}
+
+// Append 追加记录
+func (s *GormEventLog) Append(r *LogRecord) error {
+	return s.db.Create(r).Error
+}
+
+// Read 读取记录
+func (s *GormEventLog) Read(from int64, limit int) ([]*LogRecord, error) {
+	list := []*LogRecord{}
+	err := s.db.Where("seq >= ?", from).Order("seq").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// OffsetAt 按时间查找Offset
+func (s *GormEventLog) OffsetAt(t time.Time) (int64, error) {
+	list := []*LogRecord{}
+	err := s.db.Where("event_time >= ?", t).Order("seq").Limit(1).Find(&list).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(list) > 0 {
+		return list[0].Offset, nil
+	}
+	var last int64
+	err = s.db.Model(&LogRecord{}).Select("COALESCE(MAX(seq), 0)").Scan(&last).Error
+	return last + 1, err
+}
+
+// Trim 删除早于before的记录
+func (s *GormEventLog) Trim(before time.Time) error {
+	return s.db.Where("event_time < ?", before).Delete(&LogRecord{}).Error
+}
+
+// Close 关闭
+func (s *GormEventLog) Close() error {
+	return nil
+}
@@ -0,0 +1,271 @@
+package event
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// segmentExt 分段文件扩展名，文件名为分段第一条记录的Offset
+const segmentExt = ".log"
+
+type segment struct {
+	base  int64     // 第一条记录的Offset
+	first time.Time // 第一条记录的时间，空分段为零值
+	path  string
+}
+
+// FileEventLog 本地分段文件事件日志，每行一条json记录，当前分段超过大小后新建分段
+type FileEventLog struct {
+	dir         string
+	segmentSize int64
+
+	l        sync.Mutex
+	segments []*segment
+	active   *os.File
+	size     int64
+	next     int64
+}
+
+// NewFileEventLog 打开目录下的事件日志，segmentSize为分段文件大小(字节)
+func NewFileEventLog(dir string, segmentSize int64) (*FileEventLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &FileEventLog{dir: dir, segmentSize: segmentSize, next: 1}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		base, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seg := &segment{base: base, path: filepath.Join(dir, name)}
+		if r, err := readFirst(seg.path); err == nil && r != nil {
+			seg.first = r.Time
+		}
+		s.segments = append(s.segments, seg)
+	}
+	sort.Slice(s.segments, func(a, b int) bool {
+		return s.segments[a].base < s.segments[b].base
+	})
+	if len(s.segments) == 0 {
+		return s, s.roll()
+	}
+	return s, s.recover()
+}
+
+// recover 统计最后一个分段的记录数，截断异常退出时写入的不完整记录
+func (s *FileEventLog) recover() error {
+	seg := s.segments[len(s.segments)-1]
+	f, err := os.OpenFile(seg.path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(f)
+	var size, count int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		size += int64(len(line))
+		count++
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	s.active = f
+	s.size = size
+	s.next = seg.base + count
+	return nil
+}
+
+// roll 新建分段，调用方需持有锁
+func (s *FileEventLog) roll() error {
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d%s", s.next, segmentExt))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if s.active != nil {
+		s.active.Close()
+	}
+	s.active = f
+	s.size = 0
+	s.segments = append(s.segments, &segment{base: s.next, path: path})
+	return nil
+}
+
+// Append 追加记录
+func (s *FileEventLog) Append(r *LogRecord) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.active == nil {
+		return os.ErrClosed
+	}
+	if s.size >= s.segmentSize {
+		if err := s.roll(); err != nil {
+			return err
+		}
+	}
+	r.Offset = s.next
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.active.Write(append(b, '\n')); err != nil {
+		// 去掉写入了一部分的记录
+		s.active.Truncate(s.size)
+		return err
+	}
+	s.size += int64(len(b) + 1)
+	seg := s.segments[len(s.segments)-1]
+	if seg.first.IsZero() {
+		seg.first = r.Time
+	}
+	s.next++
+	return nil
+}
+
+// Read 读取记录
+func (s *FileEventLog) Read(from int64, limit int) ([]*LogRecord, error) {
+	list := []*LogRecord{}
+	err := s.scan(from, func(r *LogRecord) bool {
+		list = append(list, r)
+		return len(list) < limit
+	})
+	return list, err
+}
+
+// OffsetAt 按时间查找Offset
+func (s *FileEventLog) OffsetAt(t time.Time) (int64, error) {
+	s.l.Lock()
+	from, next := int64(1), s.next
+	for _, seg := range s.segments {
+		if seg.first.IsZero() || seg.first.After(t) {
+			break
+		}
+		from = seg.base
+	}
+	s.l.Unlock()
+	offset := next
+	err := s.scan(from, func(r *LogRecord) bool {
+		if r.Time.Before(t) {
+			return true
+		}
+		offset = r.Offset
+		return false
+	})
+	return offset, err
+}
+
+// scan 从from开始按顺序遍历记录，fn返回false时停止
+func (s *FileEventLog) scan(from int64, fn func(r *LogRecord) bool) error {
+	s.l.Lock()
+	segments := append([]*segment{}, s.segments...)
+	next := s.next
+	s.l.Unlock()
+	for idx, seg := range segments {
+		if idx+1 < len(segments) && segments[idx+1].base <= from {
+			continue
+		}
+		more, err := scanSegment(seg, from, next, fn)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanSegment(seg *segment, from, next int64, fn func(r *LogRecord) bool) (bool, error) {
+	f, err := os.Open(seg.path)
+	if os.IsNotExist(err) {
+		// 已被清理
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	for offset := seg.base; offset < next; offset++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		if offset < from {
+			continue
+		}
+		r := &LogRecord{}
+		if err := json.Unmarshal(line, r); err != nil {
+			return false, fmt.Errorf("解析事件日志[%s]的记录[%d]失败:%s", seg.path, offset, err.Error())
+		}
+		if !fn(r) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func readFirst(path string) (*LogRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil {
+		return nil, nil
+	}
+	r := &LogRecord{}
+	return r, json.Unmarshal(line, r)
+}
+
+// Trim 删除所有记录都早于before的分段，当前分段不删除
+func (s *FileEventLog) Trim(before time.Time) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	for len(s.segments) > 1 {
+		next := s.segments[1]
+		if next.first.IsZero() || next.first.After(before) {
+			break
+		}
+		if err := os.Remove(s.segments[0].path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.segments = s.segments[1:]
+	}
+	return nil
+}
+
+// Close 关闭
+func (s *FileEventLog) Close() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
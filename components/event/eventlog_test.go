@@ -0,0 +1,144 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hoorayui/core-framework/types"
+)
+
+func offsets(list []*LogRecord) []int64 {
+	var result []int64
+	for _, r := range list {
+		result = append(result, r.Offset)
+	}
+	return result
+}
+
+func TestFileEventLog(t *testing.T) {
+	dir := t.TempDir()
+	// 分段大小为1字节，每条记录一个分段
+	store, err := NewFileEventLog(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { store.Close() }()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for idx := 0; idx < 5; idx++ {
+		r := &LogRecord{ID: string(rune('a' + idx)), Topic: "order.created", Payload: "{}", Time: base.Add(time.Duration(idx) * time.Hour)}
+		if err := store.Append(r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Offset != int64(idx+1) {
+			t.Fatalf("Offset:期望%d，实际%d", idx+1, r.Offset)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(files) != 5 {
+		t.Fatalf("分段数量:%d", len(files))
+	}
+
+	list, err := store.Read(2, 2)
+	if err != nil || len(list) != 2 || list[0].Offset != 2 || list[1].ID != "c" {
+		t.Fatalf("读取记录:%v %v", offsets(list), err)
+	}
+	for at, want := range map[time.Time]int64{
+		base.Add(-time.Hour):                    1,
+		base.Add(150 * time.Minute):             4,
+		base.Add(4 * time.Hour):                 5,
+		base.Add(5 * time.Hour):                 6,
+		base.Add(4*time.Hour + time.Nanosecond): 6,
+	} {
+		if offset, err := store.OffsetAt(at); err != nil || offset != want {
+			t.Errorf("%s:期望%d，实际%d %v", at, want, offset, err)
+		}
+	}
+
+	// 只删除所有记录都早于保留时间的分段
+	if err := store.Trim(base.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	files, _ = filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(files) != 3 {
+		t.Errorf("清理后分段数量:%d", len(files))
+	}
+	if list, _ := store.Read(1, 10); len(list) != 3 || list[0].Offset != 3 {
+		t.Errorf("清理后读取记录:%v", offsets(list))
+	}
+	if offset, _ := store.OffsetAt(base); offset != 3 {
+		t.Errorf("清理后按时间查找:%d", offset)
+	}
+
+	// 异常退出时写入了不完整的记录，重新打开后截断并继续编号
+	store.Close()
+	last := files[len(files)-1]
+	f, err := os.OpenFile(last, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"offset":6,"id":`)
+	f.Close()
+	store, err = NewFileEventLog(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &LogRecord{ID: "f", Topic: "order.created", Payload: "{}", Time: base.Add(5 * time.Hour)}
+	if err := store.Append(r); err != nil || r.Offset != 6 {
+		t.Fatalf("重新打开后追加:%d %v", r.Offset, err)
+	}
+	if list, err := store.Read(5, 10); err != nil || len(list) != 2 || list[1].ID != "f" {
+		t.Errorf("重新打开后读取记录:%v %v", offsets(list), err)
+	}
+}
+
+func TestReplayFromOffset(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{EventLog: EventLogFile, EventLogDir: t.TempDir()})
+	ctx := WithRequestID(context.Background(), "req-1")
+	for _, topic := range []string{"order.created", "user.created", "order.paid"} {
+		if err := bus.Publish(ctx, topic, map[string]string{"topic": topic}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var replayed []string
+	next, err := bus.ReplayFromOffset(context.Background(), "order.*", 1, func(ctx context.Context, e *Event) error {
+		replayed = append(replayed, e.Topic+"@"+e.Metadata[MetadataOffset]+"#"+RequestID(ctx))
+		return nil
+	})
+	if err != nil || next != 4 {
+		t.Fatalf("重放结果:%d %v", next, err)
+	}
+	if got := strings.Join(replayed, ","); got != "order.created@1#req-1,order.paid@3#req-1" {
+		t.Errorf("重放的事件:%s", got)
+	}
+
+	// 处理失败时返回失败事件的Offset用于断点续放
+	errFailed := errors.New("处理失败")
+	next, err = bus.ReplayFromOffset(context.Background(), "", 1, func(ctx context.Context, e *Event) error {
+		if e.Topic == "user.created" {
+			return errFailed
+		}
+		return nil
+	})
+	if !errors.Is(err, errFailed) || next != 2 {
+		t.Errorf("重放失败:%d %v", next, err)
+	}
+
+	next, err = bus.ReplayFromTime(context.Background(), "", time.Now().Add(time.Hour), func(ctx context.Context, e *Event) error {
+		t.Errorf("不应重放:%s", e.Topic)
+		return nil
+	})
+	if err != nil || next != 4 {
+		t.Errorf("按时间重放:%d %v", next, err)
+	}
+
+	disabled := newTestBus(t, types.EventConfig{})
+	if _, err := disabled.ReplayFromOffset(context.Background(), "", 1, nil); !errors.Is(err, ErrEventLogDisabled) {
+		t.Errorf("未配置事件日志:%v", err)
+	}
+}
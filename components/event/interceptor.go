@@ -0,0 +1,217 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/hoorayui/core-framework/components/log"
+	"github.com/hoorayui/core-framework/types"
+	"github.com/sirupsen/logrus"
+)
+
+// MetadataRequestID 事件元数据中的request_id
+const MetadataRequestID = "request_id"
+
+// PublishFunc 发布事件
+type PublishFunc func(ctx context.Context, e *Event) error
+
+// PublishInterceptor 发布拦截器，调用next继续发布
+type PublishInterceptor func(next PublishFunc) PublishFunc
+
+// ConsumeInterceptor 消费拦截器，调用next执行订阅者，重试时每次执行都会经过拦截器
+type ConsumeInterceptor func(next Handler) Handler
+
+// UsePublish 注册发布拦截器，先注册的在外层
+func (i *Instance) UsePublish(interceptors ...PublishInterceptor) {
+	i.l.Lock()
+	defer i.l.Unlock()
+	i.publishInterceptors = append(i.publishInterceptors, interceptors...)
+}
+
+// UseConsume 注册消费拦截器，先注册的在外层
+func (i *Instance) UseConsume(interceptors ...ConsumeInterceptor) {
+	i.l.Lock()
+	defer i.l.Unlock()
+	i.consumeInterceptors = append(i.consumeInterceptors, interceptors...)
+}
+
+// UsePublish 注册发布拦截器
+func UsePublish(interceptors ...PublishInterceptor) {
+	GetInstance().UsePublish(interceptors...)
+}
+
+// UseConsume 注册消费拦截器
+func UseConsume(interceptors ...ConsumeInterceptor) {
+	GetInstance().UseConsume(interceptors...)
+}
+
+func (i *Instance) publishChain(publish PublishFunc) PublishFunc {
+	i.l.RLock()
+	defer i.l.RUnlock()
+	for idx := len(i.publishInterceptors) - 1; idx >= 0; idx-- {
+		publish = i.publishInterceptors[idx](publish)
+	}
+	return publish
+}
+
+func (i *Instance) consumeChain(handler Handler) Handler {
+	i.l.RLock()
+	defer i.l.RUnlock()
+	for idx := len(i.consumeInterceptors) - 1; idx >= 0; idx-- {
+		handler = i.consumeInterceptors[idx](handler)
+	}
+	return handler
+}
+
+type requestIDKey struct{}
+
+// WithRequestID 将request_id写入ctx
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID 从ctx中读取request_id，兼容gin.Context中由日志中间件设置的request_id
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	if id, ok := ctx.Value(MetadataRequestID).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// RequestIDPublishInterceptor 将ctx中的request_id写入事件元数据
+func RequestIDPublishInterceptor() PublishInterceptor {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, e *Event) error {
+			if id := RequestID(ctx); id != "" {
+				e.SetMetadata(MetadataRequestID, id)
+			}
+			return next(ctx, e)
+		}
+	}
+}
+
+// RequestIDConsumeInterceptor 将事件元数据中的request_id写入订阅者的ctx
+func RequestIDConsumeInterceptor() ConsumeInterceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, e *Event) error {
+			if id := e.Metadata[MetadataRequestID]; id != "" && RequestID(ctx) != id {
+				ctx = WithRequestID(ctx, id)
+			}
+			return next(ctx, e)
+		}
+	}
+}
+
+// RecoveryInterceptor 订阅者panic时转换为错误
+func RecoveryInterceptor() ConsumeInterceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, e *Event) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("事件[%s]订阅者panic:%v\n%s", e.Topic, r, debug.Stack())
+					err = fmt.Errorf("事件[%s]订阅者panic:%v", e.Topic, r)
+				}
+			}()
+			return next(ctx, e)
+		}
+	}
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// replyMetricTopic 响应主题的指标标签
+const replyMetricTopic = "_reply"
+
+// metricTopic 每个实例的响应主题不同，统计为同一个标签，避免指标数量随实例增长
+func metricTopic(topic string) string {
+	if strings.HasPrefix(topic, ReplyTopicPrefix) {
+		return replyMetricTopic
+	}
+	return topic
+}
+
+// MetricsPublishInterceptor 按主题和结果统计发布次数和耗时
+func MetricsPublishInterceptor() PublishInterceptor {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, e *Event) error {
+			start := time.Now()
+			err := next(ctx, e)
+			types.EventPublishCounter.WithLabelValues(metricTopic(e.Topic), outcome(err)).Inc()
+			types.EventPublishHistogram.WithLabelValues(metricTopic(e.Topic), outcome(err)).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// MetricsConsumeInterceptor 按主题和结果统计消费次数和耗时
+func MetricsConsumeInterceptor() ConsumeInterceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, e *Event) error {
+			start := time.Now()
+			err := next(ctx, e)
+			types.EventConsumeCounter.WithLabelValues(metricTopic(e.Topic), outcome(err)).Inc()
+			types.EventConsumeHistogram.WithLabelValues(metricTopic(e.Topic), outcome(err)).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+func eventFields(ctx context.Context, e *Event) logrus.Fields {
+	fields := logrus.Fields{
+		"event_id": e.ID,
+		"topic":    e.Topic,
+	}
+	if e.Key != "" {
+		fields["key"] = e.Key
+	}
+	if id := RequestID(ctx); id != "" {
+		fields[MetadataRequestID] = id
+	} else if id := e.Metadata[MetadataRequestID]; id != "" {
+		fields[MetadataRequestID] = id
+	}
+	return fields
+}
+
+// LogPublishInterceptor 记录发布日志
+func LogPublishInterceptor() PublishInterceptor {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, e *Event) error {
+			err := next(ctx, e)
+			entry := log.WithFields(eventFields(ctx, e))
+			if err != nil {
+				entry.WithField("error", err.Error()).Error("事件发布失败")
+			} else {
+				entry.Info("事件已发布")
+			}
+			return err
+		}
+	}
+}
+
+// LogConsumeInterceptor 记录消费日志
+func LogConsumeInterceptor() ConsumeInterceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, e *Event) error {
+			start := time.Now()
+			err := next(ctx, e)
+			entry := log.WithFields(eventFields(ctx, e)).WithField("cost", time.Since(start).String())
+			if err != nil {
+				entry.WithField("error", err.Error()).Error("事件处理失败")
+			} else {
+				entry.Info("事件已处理")
+			}
+			return err
+		}
+	}
+}
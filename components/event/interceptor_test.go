@@ -0,0 +1,104 @@
+package event
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hoorayui/core-framework/components/log"
+	"github.com/hoorayui/core-framework/types"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestInterceptorOrder(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	var l sync.Mutex
+	var calls []string
+	record := func(name string) {
+		l.Lock()
+		defer l.Unlock()
+		calls = append(calls, name)
+	}
+	publishInterceptor := func(name string) PublishInterceptor {
+		return func(next PublishFunc) PublishFunc {
+			return func(ctx context.Context, e *Event) error {
+				record(name + ".before")
+				err := next(ctx, e)
+				record(name + ".after")
+				return err
+			}
+		}
+	}
+	consumeInterceptor := func(name string) ConsumeInterceptor {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, e *Event) error {
+				record(name + ".before")
+				err := next(ctx, e)
+				record(name + ".after")
+				return err
+			}
+		}
+	}
+	bus.UsePublish(publishInterceptor("p1"), publishInterceptor("p2"))
+	bus.UseConsume(consumeInterceptor("c1"))
+	bus.UseConsume(consumeInterceptor("c2"))
+	bus.Subscribe("order.created", func(ctx context.Context, e *Event) error {
+		record("handler")
+		return nil
+	})
+
+	bus.Publish(context.Background(), "order.created", nil)
+	want := "p1.before,p2.before,c1.before,c2.before,handler,c2.after,c1.after,p2.after,p1.after"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("拦截器顺序不正确:\n期望%s\n实际%s", want, got)
+	}
+}
+
+func TestRequestIDInterceptor(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	bus.UsePublish(RequestIDPublishInterceptor())
+	bus.UseConsume(RequestIDConsumeInterceptor())
+	var got string
+	bus.Subscribe("order.created", func(ctx context.Context, e *Event) error {
+		got = RequestID(ctx)
+		return nil
+	})
+
+	// 传输层收到的事件没有发布方的ctx，只能从元数据中读取
+	ctx := WithRequestID(context.Background(), "req-1")
+	e := &Event{ID: "e1", Topic: "order.created"}
+	bus.publishChain(func(ctx context.Context, e *Event) error {
+		return bus.deliver(context.Background(), e, bus.match(e.Topic))
+	})(ctx, e)
+	if e.Metadata[MetadataRequestID] != "req-1" || got != "req-1" {
+		t.Errorf("request_id未传递:%v %q", e.Metadata, got)
+	}
+}
+
+func TestRecoveryAndLogInterceptor(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	hook := test.NewLocal(log.GetInstance().Logger())
+	bus.UsePublish(LogPublishInterceptor())
+	bus.UseConsume(LogConsumeInterceptor(), RecoveryInterceptor())
+	bus.Subscribe("order.created", func(ctx context.Context, e *Event) error {
+		panic("处理异常")
+	})
+
+	err := bus.Publish(context.Background(), "order.created", nil, WithKey("1"))
+	if err == nil || !strings.Contains(err.Error(), "panic") {
+		t.Fatalf("订阅者panic时应返回错误:%v", err)
+	}
+	messages := map[string]*logrus.Entry{}
+	for _, entry := range hook.AllEntries() {
+		messages[entry.Message] = entry
+	}
+	consumed, published := messages["事件处理失败"], messages["事件发布失败"]
+	if consumed == nil || published == nil {
+		t.Fatalf("未记录消费和发布日志:%v", hook.AllEntries())
+	}
+	if consumed.Data["topic"] != "order.created" || consumed.Data["key"] != "1" || consumed.Data["error"] == nil {
+		t.Errorf("消费日志字段不正确:%v", consumed.Data)
+	}
+}
@@ -1,32 +1,265 @@
 package event
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
 	"github.com/hoorayui/core-framework/components/log"
+	"github.com/hoorayui/core-framework/types"
+	"github.com/hoorayui/core-framework/util"
 )
 
-// registerHook 注册回调函数
-func registerHook(e string, hook func(string, interface{}) error) {
-	GetInstance().l.Lock()
-	defer GetInstance().l.Unlock()
-	if watcher, ok := GetInstance().watcher[e]; ok {
-		watcher = append(watcher, hook)
-		GetInstance().watcher[e] = watcher
-	} else {
-		watcher = []func(string, interface{}) error{hook}
-		GetInstance().watcher[e] = watcher
-	}
-}
-func trigger(e string) {
-	// get listeners and run them
-	watchers, ok := GetInstance().watcher[e]
-	if !ok {
-		log.Errorf("不支持的事件[%s]", e)
-		return
-	}
-	for _, listener := range watchers {
-		err := listener(e, nil)
-		if err != nil {
-			log.Errorf("事件[%s]回调执行失败:%s", e, err.Error())
+// Event 事件
+type Event struct {
+	ID       string            `json:"id"`
+	Topic    string            `json:"topic"`
+	Key      string            `json:"key,omitempty"`
+	Payload  interface{}       `json:"payload"`
+	Time     time.Time         `json:"time"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// SetMetadata 设置元数据，元数据随事件跨进程传输
+func (e *Event) SetMetadata(key, value string) {
+	if e.Metadata == nil {
+		e.Metadata = map[string]string{}
+	}
+	e.Metadata[key] = value
+}
+
+// Decode 将payload解析到v，跨进程传输的事件payload为json.RawMessage
+func (e *Event) Decode(v interface{}) error {
+	if raw, ok := e.Payload.(json.RawMessage); ok {
+		return json.Unmarshal(raw, v)
+	}
+	b, err := json.Marshal(e.Payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Handler 事件处理函数
+type Handler func(ctx context.Context, e *Event) error
+
+// Subscription 订阅，用于取消订阅
+type Subscription struct {
+	id      uint64
+	topic   string
+	group   string
+	name    string
+	retry   *RetryPolicy
+	handler Handler
+	once    bool
+	done    int32
+	bus     *Instance
+}
+
+// SubscribeOption 订阅选项
+type SubscribeOption func(*Subscription)
+
+// WithGroup 设置消费组。使用传输层时，同一消费组内只有一个实例处理事件(竞争消费)，
+// 未设置消费组时所有实例都会收到事件(广播)
+func WithGroup(group string) SubscribeOption {
+	return func(s *Subscription) {
+		s.group = group
+	}
+}
+
+// Topic 订阅的主题
+func (s *Subscription) Topic() string {
+	return s.topic
+}
+
+// Unsubscribe 取消订阅，可重复调用
+func (s *Subscription) Unsubscribe() {
+	s.bus.unsubscribe(s)
+}
+
+// Subscribe 订阅事件，主题支持通配符，见WildcardOne、WildcardRest。
+// 配置了传输层时向传输层订阅，传输层订阅失败或不支持通配符时返回错误
+func (i *Instance) Subscribe(topic string, handler Handler, opts ...SubscribeOption) (*Subscription, error) {
+	return i.subscribe(topic, handler, false, opts)
+}
+
+// SubscribeOnce 订阅事件，处理一次后自动取消订阅
+func (i *Instance) SubscribeOnce(topic string, handler Handler, opts ...SubscribeOption) (*Subscription, error) {
+	return i.subscribe(topic, handler, true, opts)
+}
+
+func (i *Instance) subscribe(topic string, handler Handler, once bool, opts []SubscribeOption) (*Subscription, error) {
+	i.l.Lock()
+	defer i.l.Unlock()
+	i.nextID++
+	sub := &Subscription{
+		id:      i.nextID,
+		topic:   topic,
+		handler: handler,
+		once:    once,
+		bus:     i,
+	}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	if err := i.remoteSubscribe(sub); err != nil {
+		return nil, err
+	}
+	i.watcher[topic] = append(i.watcher[topic], sub)
+	if IsWildcard(topic) {
+		i.wildcards[topic] = true
+	}
+	return sub, nil
+}
+
+func (i *Instance) unsubscribe(s *Subscription) {
+	i.l.Lock()
+	defer i.l.Unlock()
+	subs := i.watcher[s.topic]
+	for idx, sub := range subs {
+		if sub == s {
+			// 复制一份，避免影响正在分发的事件
+			i.watcher[s.topic] = append(append([]*Subscription{}, subs[:idx]...), subs[idx+1:]...)
+			i.remoteUnsubscribe(s)
+			break
 		}
 	}
+	if len(i.watcher[s.topic]) == 0 {
+		delete(i.watcher, s.topic)
+		delete(i.wildcards, s.topic)
+	}
+}
+
+// PublishOption 发布选项
+type PublishOption func(*publishOptions)
+
+type publishOptions struct {
+	key      string
+	sync     bool
+	local    bool
+	metadata map[string]string
+}
+
+// WithKey 设置事件key，异步模式下相同key的事件按发布顺序处理
+func WithKey(key string) PublishOption {
+	return func(o *publishOptions) {
+		o.key = key
+	}
+}
+
+// WithSync 异步模式下仍同步执行订阅者并返回错误
+func WithSync() PublishOption {
+	return func(o *publishOptions) {
+		o.sync = true
+	}
+}
+
+// WithMetadata 设置事件元数据
+func WithMetadata(key, value string) PublishOption {
+	return func(o *publishOptions) {
+		if o.metadata == nil {
+			o.metadata = map[string]string{}
+		}
+		o.metadata[key] = value
+	}
+}
+
+// WithLocal 配置了传输层时，只在当前进程内分发
+func WithLocal() PublishOption {
+	return func(o *publishOptions) {
+		o.local = true
+	}
+}
+
+// Publish 发布事件。同步模式下执行所有订阅者并返回订阅者的错误，
+// 异步模式下事件进入队列后返回，订阅者的错误只记录日志。
+// 配置了传输层时事件发送到传输层，由各实例的订阅者接收
+func (i *Instance) Publish(ctx context.Context, topic string, payload interface{}, opts ...PublishOption) error {
+	o := &publishOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	e := &Event{
+		ID:      util.NewUUIDString(""),
+		Topic:   topic,
+		Key:     o.key,
+		Payload: payload,
+		Time:    util.Now(),
+	}
+	for k, v := range o.metadata {
+		e.SetMetadata(k, v)
+	}
+	return i.publishEvent(ctx, e, o)
+}
+
+// publishEvent 经过发布拦截器发布事件
+func (i *Instance) publishEvent(ctx context.Context, e *Event, o *publishOptions) error {
+	return i.publishChain(func(ctx context.Context, e *Event) error {
+		return i.publish(ctx, e, o)
+	})(ctx, e)
+}
+
+// publish 发送到传输层或分发给本进程的订阅者
+func (i *Instance) publish(ctx context.Context, e *Event, o *publishOptions) error {
+	topic := e.Topic
+	if err := i.appendLog(ctx, e); err != nil {
+		return fmt.Errorf("事件[%s]写入事件日志失败:%w", topic, err)
+	}
+	if i.transport != nil && !o.local {
+		return i.transport.Publish(ctx, e)
+	}
+	subs := i.match(topic)
+	if len(subs) == 0 {
+		// 没有订阅者不是错误
+		if i.config.NoSubscriberMetric {
+			types.EventNoSubscriberCounter.WithLabelValues(metricTopic(topic)).Inc()
+		}
+		return nil
+	}
+	if i.dispatcher != nil && !o.sync {
+		detached, copied := detach(ctx, e)
+		return i.dispatcher.dispatch(ctx, &task{
+			ctx:  detached,
+			e:    copied,
+			subs: subs,
+		})
+	}
+	return i.deliver(ctx, e, subs)
+}
+
+// deliver 依次执行订阅者
+func (i *Instance) deliver(ctx context.Context, e *Event, subs []*Subscription) error {
+	topic := e.Topic
+	var errs []error
+	for _, sub := range subs {
+		if sub.once {
+			if !atomic.CompareAndSwapInt32(&sub.done, 0, 1) {
+				continue
+			}
+			sub.Unsubscribe()
+		}
+		if err := i.invoke(ctx, sub, e); err != nil {
+			log.Errorf("事件[%s]回调执行失败:%s", topic, err.Error())
+			errs = append(errs, fmt.Errorf("事件[%s]订阅者[%d]执行失败:%w", topic, sub.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Subscribe 订阅事件
+func Subscribe(topic string, handler Handler, opts ...SubscribeOption) (*Subscription, error) {
+	return GetInstance().Subscribe(topic, handler, opts...)
+}
+
+// SubscribeOnce 订阅事件，处理一次后自动取消订阅
+func SubscribeOnce(topic string, handler Handler, opts ...SubscribeOption) (*Subscription, error) {
+	return GetInstance().SubscribeOnce(topic, handler, opts...)
+}
+
+// Publish 发布事件
+func Publish(ctx context.Context, topic string, payload interface{}, opts ...PublishOption) error {
+	return GetInstance().Publish(ctx, topic, payload, opts...)
 }
@@ -0,0 +1,275 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hoorayui/core-framework/components/log"
+	"github.com/hoorayui/core-framework/components/mysql"
+	"github.com/hoorayui/core-framework/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 发件箱消息状态
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed" // 投递次数达到上限，不再投递
+)
+
+// ErrOutboxDisabled 未启用事务发件箱
+var ErrOutboxDisabled = errors.New("未启用事务发件箱")
+
+// OutboxMessage 发件箱消息，ID即投递后的事件ID，重复投递时订阅者可按事件ID去重
+type OutboxMessage struct {
+	ID        string     `json:"id" gorm:"primaryKey;size:64"`
+	Topic     string     `json:"topic" gorm:"size:255"`
+	Key       string     `json:"key" gorm:"size:255"`
+	Payload   string     `json:"payload" gorm:"type:text"`
+	Metadata  string     `json:"metadata" gorm:"type:text"`
+	Status    string     `json:"status" gorm:"size:16;index:idx_event_outbox_status,priority:1"`
+	Attempts  int        `json:"attempts"`
+	Error     string     `json:"error" gorm:"type:text"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index:idx_event_outbox_status,priority:2"`
+	SentAt    *time.Time `json:"sent_at"`
+	// NextAttemptAt 下次投递时间，投递失败后按指数退避，投递中的消息为领取的超时时间
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+}
+
+// TableName 表名
+func (OutboxMessage) TableName() string {
+	return "event_outbox"
+}
+
+func (m *OutboxMessage) event() *Event {
+	e := &Event{
+		ID:      m.ID,
+		Topic:   m.Topic,
+		Key:     m.Key,
+		Payload: json.RawMessage(m.Payload),
+		Time:    m.CreatedAt,
+	}
+	if m.Metadata != "" {
+		json.Unmarshal([]byte(m.Metadata), &e.Metadata)
+	}
+	return e
+}
+
+// PublishTx 在事务中写入发件箱，事务提交后由投递器发布到事件总线，事务回滚时事件不会发布。
+// 发布选项只有WithKey生效
+func (i *Instance) PublishTx(ctx context.Context, tx *mysql.DB, topic string, payload interface{}, opts ...PublishOption) error {
+	if i.outbox == nil {
+		return ErrOutboxDisabled
+	}
+	o := &publishOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	msg := &OutboxMessage{
+		ID:        util.NewUUIDString(""),
+		Topic:     topic,
+		Key:       o.key,
+		Payload:   string(b),
+		Status:    OutboxPending,
+		CreatedAt: util.Now(),
+	}
+	msg.NextAttemptAt = &msg.CreatedAt
+	if id := RequestID(ctx); id != "" {
+		metadata, _ := json.Marshal(map[string]string{MetadataRequestID: id})
+		msg.Metadata = string(metadata)
+	}
+	return tx.GetDB().WithContext(ctx).Create(msg).Error
+}
+
+// PublishTx 在事务中写入发件箱
+func PublishTx(ctx context.Context, tx *mysql.DB, topic string, payload interface{}, opts ...PublishOption) error {
+	return GetInstance().PublishTx(ctx, tx, topic, payload, opts...)
+}
+
+// Outbox 发件箱投递器，未启用时为nil
+func (i *Instance) Outbox() *OutboxRelay {
+	return i.outbox
+}
+
+// OutboxRelay 发件箱投递器，按写入顺序领取消息，领取后提交事务再投递，投递时不持有行锁。
+// 投递失败的消息按指数退避重试，不阻塞之后的消息。
+// 投递成功但标记失败、或投递超过领取超时时间时消息会再次投递，订阅者需按事件ID去重
+type OutboxRelay struct {
+	db  *gorm.DB
+	bus *Instance
+
+	Interval     time.Duration // 投递间隔
+	BatchSize    int           // 每批投递的数量
+	MaxAttempts  int           // 最大投递次数，达到后标记为failed
+	Retention    time.Duration // 已发送消息的保留时间
+	ClaimTimeout time.Duration // 领取的超时时间，实例异常退出时超时后由其他实例重新投递
+	Backoff      time.Duration // 第一次投递失败后的重试间隔，之后每次翻倍
+	MaxBackoff   time.Duration // 最大重试间隔
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newOutboxRelay 创建投递器并自动建表
+func newOutboxRelay(db *gorm.DB, bus *Instance) (*OutboxRelay, error) {
+	if err := db.AutoMigrate(&OutboxMessage{}); err != nil {
+		return nil, err
+	}
+	return &OutboxRelay{
+		db:           db,
+		bus:          bus,
+		Interval:     time.Second,
+		BatchSize:    100,
+		MaxAttempts:  10,
+		Retention:    7 * 24 * time.Hour,
+		ClaimTimeout: time.Minute,
+		Backoff:      time.Second,
+		MaxBackoff:   10 * time.Minute,
+	}, nil
+}
+
+// start 启动后台投递
+func (r *OutboxRelay) start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.loop()
+}
+
+// close 停止后台投递，等待正在进行的投递完成
+func (r *OutboxRelay) close() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.stop = nil
+}
+
+func (r *OutboxRelay) loop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	lastPurge := time.Now()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+		for {
+			n, err := r.RelayOnce(context.Background())
+			if err != nil {
+				log.Errorf("投递发件箱消息失败:%s", err.Error())
+			}
+			if err != nil || n < r.BatchSize {
+				break
+			}
+		}
+		if time.Since(lastPurge) >= time.Hour {
+			lastPurge = time.Now()
+			if err := r.Purge(); err != nil {
+				log.Errorf("清理发件箱消息失败:%s", err.Error())
+			}
+		}
+	}
+}
+
+// RelayOnce 领取并投递一批到期的消息，返回投递成功的数量
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	list, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	var errs []error
+	for _, msg := range list {
+		e := msg.event()
+		publishCtx := ctx
+		if id := e.Metadata[MetadataRequestID]; id != "" {
+			publishCtx = WithRequestID(ctx, id)
+		}
+		msg.Attempts++
+		if err := r.bus.publishEvent(publishCtx, e, &publishOptions{key: msg.Key}); err != nil {
+			log.Errorf("发件箱消息[%s]第%d次投递失败:%s", msg.ID, msg.Attempts, err.Error())
+			status := OutboxPending
+			if msg.Attempts >= r.MaxAttempts {
+				status = OutboxFailed
+			}
+			errs = append(errs, r.update(ctx, msg, map[string]interface{}{
+				"status":          status,
+				"attempts":        msg.Attempts,
+				"error":           err.Error(),
+				"next_attempt_at": util.Now().Add(r.backoff(msg.Attempts)),
+			}))
+			continue
+		}
+		err := r.update(ctx, msg, map[string]interface{}{
+			"status":   OutboxSent,
+			"attempts": msg.Attempts,
+			"error":    "",
+			"sent_at":  util.Now(),
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
+
+// claim 领取一批到期的消息，将下次投递时间设为领取超时时间后提交，其他实例不会重复领取
+func (r *OutboxRelay) claim(ctx context.Context) ([]*OutboxMessage, error) {
+	var list []*OutboxMessage
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := util.Now()
+		// 多实例部署时跳过其他实例正在领取的消息
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", OutboxPending, now).
+			Order("created_at").
+			Limit(r.BatchSize).
+			Find(&list).Error
+		if err != nil || len(list) == 0 {
+			return err
+		}
+		ids := make([]string, 0, len(list))
+		for _, msg := range list {
+			ids = append(ids, msg.ID)
+		}
+		return tx.Model(&OutboxMessage{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(r.ClaimTimeout)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *OutboxRelay) update(ctx context.Context, msg *OutboxMessage, values map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(msg).Updates(values).Error
+}
+
+// backoff 第attempts次投递失败后的重试间隔
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	d := r.Backoff
+	for n := 1; n < attempts && d < r.MaxBackoff; n++ {
+		d *= 2
+	}
+	if r.MaxBackoff > 0 && d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	return d
+}
+
+// Purge 删除超过保留时间的已发送消息
+func (r *OutboxRelay) Purge() error {
+	return r.db.Where("status = ? AND sent_at < ?", OutboxSent, util.Now().Add(-r.Retention)).
+		Delete(&OutboxMessage{}).Error
+}
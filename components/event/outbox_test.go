@@ -0,0 +1,247 @@
+package event
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hoorayui/core-framework/types"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// fakeOutboxDB 测试用的数据库，查询返回待发送的消息，并记录执行的语句
+type fakeOutboxDB struct {
+	l         sync.Mutex
+	pending   []*OutboxMessage
+	queries   []string
+	execs     []fakeExec
+	commits   int
+	rollbacks int
+}
+
+type fakeExec struct {
+	query string
+	args  []driver.Value
+}
+
+func (db *fakeOutboxDB) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeOutboxConn{db: db}, nil
+}
+
+func (db *fakeOutboxDB) Driver() driver.Driver {
+	return nil
+}
+
+// gorm 打开数据库
+func (db *fakeOutboxDB) gorm(t *testing.T) *gorm.DB {
+	t.Helper()
+	g, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(db),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// statuses 按执行顺序返回更新的消息ID和状态
+func (db *fakeOutboxDB) statuses() []string {
+	db.l.Lock()
+	defer db.l.Unlock()
+	var list []string
+	for _, e := range db.execs {
+		var id, status string
+		for _, arg := range e.args {
+			switch v := arg.(type) {
+			case string:
+				if v == OutboxPending || v == OutboxSent || v == OutboxFailed {
+					status = v
+				} else if strings.HasPrefix(v, "m") {
+					id = v
+				}
+			}
+		}
+		list = append(list, id+":"+status)
+	}
+	return list
+}
+
+type fakeOutboxConn struct {
+	db *fakeOutboxDB
+}
+
+func (c *fakeOutboxConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeOutboxStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeOutboxConn) Close() error {
+	return nil
+}
+
+func (c *fakeOutboxConn) Begin() (driver.Tx, error) {
+	return &fakeOutboxTx{db: c.db}, nil
+}
+
+type fakeOutboxTx struct {
+	db *fakeOutboxDB
+}
+
+func (tx *fakeOutboxTx) Commit() error {
+	tx.db.l.Lock()
+	defer tx.db.l.Unlock()
+	tx.db.commits++
+	return nil
+}
+
+func (tx *fakeOutboxTx) Rollback() error {
+	tx.db.l.Lock()
+	defer tx.db.l.Unlock()
+	tx.db.rollbacks++
+	return nil
+}
+
+type fakeOutboxStmt struct {
+	db    *fakeOutboxDB
+	query string
+}
+
+func (s *fakeOutboxStmt) Close() error {
+	return nil
+}
+
+func (s *fakeOutboxStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeOutboxStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.l.Lock()
+	defer s.db.l.Unlock()
+	s.db.execs = append(s.db.execs, fakeExec{query: s.query, args: args})
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeOutboxStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.l.Lock()
+	defer s.db.l.Unlock()
+	s.db.queries = append(s.db.queries, s.query)
+	rows := &fakeOutboxRows{}
+	for _, m := range s.db.pending {
+		rows.values = append(rows.values, []driver.Value{
+			m.ID, m.Topic, m.Key, m.Payload, m.Metadata, m.Status, int64(m.Attempts), m.Error, m.CreatedAt, nil, nil,
+		})
+	}
+	return rows, nil
+}
+
+type fakeOutboxRows struct {
+	values [][]driver.Value
+}
+
+func (r *fakeOutboxRows) Columns() []string {
+	return []string{"id", "topic", "key", "payload", "metadata", "status", "attempts", "error", "created_at", "sent_at", "next_attempt_at"}
+}
+
+func (r *fakeOutboxRows) Close() error {
+	return nil
+}
+
+func (r *fakeOutboxRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestOutboxRelay(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	now := time.Now()
+	db := &fakeOutboxDB{pending: []*OutboxMessage{
+		{ID: "m1", Topic: "order.created", Key: "1", Payload: `{"id":1}`, Metadata: `{"request_id":"req-1"}`, Status: OutboxPending, CreatedAt: now},
+		{ID: "m2", Topic: "order.created", Key: "2", Payload: `{"id":2}`, Status: OutboxPending, Attempts: 1, CreatedAt: now},
+		{ID: "m3", Topic: "order.created", Key: "3", Payload: `{"id":3}`, Status: OutboxPending, CreatedAt: now},
+		{ID: "m4", Topic: "order.created", Key: "4", Payload: `{"id":4}`, Status: OutboxPending, CreatedAt: now},
+	}}
+	relay := &OutboxRelay{db: db.gorm(t), bus: bus, BatchSize: 10, MaxAttempts: 2, ClaimTimeout: time.Minute, Backoff: time.Second, MaxBackoff: time.Minute}
+
+	var received []string
+	var requestID string
+	bus.Subscribe("order.created", func(ctx context.Context, e *Event) error {
+		db.l.Lock()
+		commits := db.commits
+		db.l.Unlock()
+		if commits != 1 {
+			t.Errorf("投递消息[%s]时领取的事务未提交", e.ID)
+		}
+		if e.Key == "2" || e.Key == "4" {
+			return errors.New("处理失败")
+		}
+		received = append(received, e.ID)
+		if e.ID == "m1" {
+			requestID = RequestID(ctx)
+		}
+		return nil
+	})
+
+	sent, err := relay.RelayOnce(context.Background())
+	if err != nil || sent != 2 {
+		t.Fatalf("投递结果:%d %v", sent, err)
+	}
+	if len(db.queries) != 1 {
+		t.Fatalf("查询次数:%d", len(db.queries))
+	}
+	query := db.queries[0]
+	for _, want := range []string{"status = ?", "next_attempt_at <= ?", "ORDER BY created_at", "LIMIT 10", "FOR UPDATE SKIP LOCKED"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("查询语句缺少%s:%s", want, query)
+		}
+	}
+	if strings.Join(received, ",") != "m1,m3" || requestID != "req-1" {
+		t.Errorf("投递失败的消息不应阻塞之后的消息:%v %q", received, requestID)
+	}
+
+	// 先领取本批消息，再逐条更新状态
+	claim := db.execs[0]
+	if !strings.Contains(claim.query, "next_attempt_at") || len(claim.args) != 5 {
+		t.Errorf("领取语句:%s %v", claim.query, claim.args)
+	}
+	if lease, ok := claim.args[0].(time.Time); !ok || lease.Sub(now) < time.Minute-time.Second {
+		t.Errorf("领取后的下次投递时间应为领取超时时间:%v", claim.args[0])
+	}
+	// m2达到最大投递次数后标记为failed，m4按退避时间重试
+	if got := strings.Join(db.statuses()[1:], ","); got != "m1:sent,m2:failed,m3:sent,m4:pending" {
+		t.Errorf("消息状态:%s", got)
+	}
+	retry := db.execs[len(db.execs)-1]
+	if !strings.Contains(retry.query, "next_attempt_at") {
+		t.Errorf("投递失败时应设置下次投递时间:%s", retry.query)
+	}
+	if db.commits != 1 || db.rollbacks != 0 {
+		t.Errorf("事务提交%d次，回滚%d次", db.commits, db.rollbacks)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	relay := &OutboxRelay{Backoff: time.Second, MaxBackoff: 10 * time.Second}
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 5: 10 * time.Second, 100: 10 * time.Second} {
+		if got := relay.backoff(attempts); got != want {
+			t.Errorf("第%d次失败后的重试间隔:期望%s，实际%s", attempts, want, got)
+		}
+	}
+}
+
+func TestPublishTxDisabled(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	if err := bus.PublishTx(context.Background(), nil, "order.created", nil); !errors.Is(err, ErrOutboxDisabled) {
+		t.Errorf("未启用发件箱:%v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hoorayui/core-framework/components/log"
+	"github.com/hoorayui/core-framework/util"
+)
+
+// 请求响应使用的元数据
+const (
+	MetadataReplyTo       = "reply_to"       // 响应主题
+	MetadataCorrelationID = "correlation_id" // 请求标识，响应时原样返回
+)
+
+// ReplyTopicPrefix 响应主题前缀，每个实例使用独立的响应主题
+const ReplyTopicPrefix = "_reply."
+
+// DefaultRequestTimeout ctx未设置超时时间时的请求超时时间
+const DefaultRequestTimeout = 10 * time.Second
+
+// ErrNoResponder 未配置传输层且本进程内没有处理方
+var ErrNoResponder = errors.New("没有处理请求的订阅者")
+
+// ReplyError 处理方返回的错误
+type ReplyError struct {
+	Topic   string
+	Message string
+}
+
+func (e *ReplyError) Error() string {
+	return fmt.Sprintf("请求[%s]处理失败:%s", e.Topic, e.Message)
+}
+
+// reply 响应内容
+type reply struct {
+	Payload interface{} `json:"payload"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// requester 等待响应的请求，按correlation_id分发响应
+type requester struct {
+	topic   string
+	sub     *Subscription // 响应主题的订阅，订阅失败时下次请求重新订阅
+	l       sync.Mutex
+	pending map[string]chan *Event
+}
+
+func (r *requester) add(id string) chan *Event {
+	r.l.Lock()
+	defer r.l.Unlock()
+	ch := make(chan *Event, 1)
+	r.pending[id] = ch
+	return ch
+}
+
+func (r *requester) remove(id string) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	delete(r.pending, id)
+}
+
+// receive 只接收第一个响应，请求已结束的响应直接丢弃
+func (r *requester) receive(ctx context.Context, e *Event) error {
+	r.l.Lock()
+	ch, ok := r.pending[e.Metadata[MetadataCorrelationID]]
+	r.l.Unlock()
+	if ok {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	return nil
+}
+
+// replies 首次请求时订阅当前实例的响应主题
+func (i *Instance) replies() (*requester, error) {
+	i.l.Lock()
+	if i.requests == nil {
+		i.requests = &requester{
+			topic:   ReplyTopicPrefix + util.NewUUIDString(""),
+			pending: map[string]chan *Event{},
+		}
+	}
+	r := i.requests
+	i.l.Unlock()
+	r.l.Lock()
+	defer r.l.Unlock()
+	if r.sub == nil {
+		sub, err := i.Subscribe(r.topic, r.receive)
+		if err != nil {
+			return nil, err
+		}
+		r.sub = sub
+	}
+	return r, nil
+}
+
+// closeReplies 取消订阅并删除当前实例的响应主题，重新初始化后响应主题已迁移到新实例时不处理
+func (i *Instance) closeReplies() {
+	i.l.RLock()
+	r := i.requests
+	i.l.RUnlock()
+	if r == nil {
+		return
+	}
+	r.l.Lock()
+	defer r.l.Unlock()
+	if r.sub == nil || r.sub.bus != i {
+		return
+	}
+	r.sub.Unsubscribe()
+	r.sub = nil
+	if deleter, ok := i.transport.(TopicDeleter); ok {
+		if err := deleter.DeleteTopic(r.topic); err != nil {
+			log.Errorf("删除响应主题[%s]失败:%s", r.topic, err.Error())
+		}
+	}
+}
+
+// Request 发布请求并等待第一个响应，ctx未设置超时时间时使用DefaultRequestTimeout。
+// 返回的响应事件可通过Decode解析处理方的返回值，处理方返回错误时返回*ReplyError
+func (i *Instance) Request(ctx context.Context, topic string, payload interface{}, opts ...PublishOption) (*Event, error) {
+	if i.transport == nil && len(i.match(topic)) == 0 {
+		return nil, fmt.Errorf("%w[%s]", ErrNoResponder, topic)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRequestTimeout)
+		defer cancel()
+	}
+	r, err := i.replies()
+	if err != nil {
+		return nil, err
+	}
+	id := util.NewUUIDString("")
+	ch := r.add(id)
+	defer r.remove(id)
+	opts = append(opts, WithMetadata(MetadataReplyTo, r.topic), WithMetadata(MetadataCorrelationID, id))
+	if err := i.Publish(ctx, topic, payload, opts...); err != nil {
+		return nil, err
+	}
+	select {
+	case e := <-ch:
+		return decodeReply(topic, e)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("请求[%s]等待响应失败:%w", topic, ctx.Err())
+	}
+}
+
+// decodeReply 将响应事件的payload替换为处理方的返回值
+func decodeReply(topic string, e *Event) (*Event, error) {
+	resp := *e
+	switch p := e.Payload.(type) {
+	case reply:
+		if p.Error != "" {
+			return nil, &ReplyError{Topic: topic, Message: p.Error}
+		}
+		resp.Payload = p.Payload
+	case json.RawMessage:
+		raw := struct {
+			Payload json.RawMessage `json:"payload"`
+			Error   string          `json:"error"`
+		}{}
+		if err := json.Unmarshal(p, &raw); err != nil {
+			return nil, fmt.Errorf("解析请求[%s]的响应失败:%s", topic, err.Error())
+		}
+		if raw.Error != "" {
+			return nil, &ReplyError{Topic: topic, Message: raw.Error}
+		}
+		resp.Payload = raw.Payload
+	default:
+		return nil, fmt.Errorf("请求[%s]的响应格式不正确[%T]", topic, e.Payload)
+	}
+	return &resp, nil
+}
+
+// Handle 注册请求处理方，返回值发送给请求方，多实例部署时通常配合WithGroup只由一个实例响应。
+// 收到的事件不是请求时只执行处理方
+func (i *Instance) Handle(topic string, fn func(ctx context.Context, req *Event) (interface{}, error), opts ...SubscribeOption) (*Subscription, error) {
+	var sub *Subscription
+	capture := func(s *Subscription) {
+		sub = s
+	}
+	return i.Subscribe(topic, func(ctx context.Context, e *Event) error {
+		resp, err := fn(ctx, e)
+		replyTo := e.Metadata[MetadataReplyTo]
+		if replyTo == "" {
+			return err
+		}
+		r := reply{Payload: resp}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		// 组件重新初始化后订阅会迁移到新实例，通过sub.bus发送响应
+		return sub.bus.Publish(ctx, replyTo, r, WithMetadata(MetadataCorrelationID, e.Metadata[MetadataCorrelationID]))
+	}, append(opts, capture)...)
+}
+
+// Request 发布请求并等待响应
+func Request(ctx context.Context, topic string, payload interface{}, opts ...PublishOption) (*Event, error) {
+	return GetInstance().Request(ctx, topic, payload, opts...)
+}
+
+// Handle 注册请求处理方
+func Handle(topic string, fn func(ctx context.Context, req *Event) (interface{}, error), opts ...SubscribeOption) (*Subscription, error) {
+	return GetInstance().Handle(topic, fn, opts...)
+}
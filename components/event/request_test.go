@@ -0,0 +1,215 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hoorayui/core-framework/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// loopbackTransport 测试用的传输层，发布的事件直接交给本进程接收
+type loopbackTransport struct {
+	bus *Instance
+
+	l       sync.Mutex
+	topics  map[string]bool
+	deleted []string
+}
+
+func newLoopbackTransport(bus *Instance) *loopbackTransport {
+	return &loopbackTransport{bus: bus, topics: map[string]bool{}}
+}
+
+func (t *loopbackTransport) Name() string {
+	return "loopback"
+}
+
+func (t *loopbackTransport) Publish(ctx context.Context, e *Event) error {
+	return t.bus.receive(ctx, e, "")
+}
+
+func (t *loopbackTransport) Subscribe(topic, group string) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+	t.topics[topic] = true
+	return nil
+}
+
+func (t *loopbackTransport) Unsubscribe(topic, group string) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+	delete(t.topics, topic)
+	return nil
+}
+
+func (t *loopbackTransport) DeleteTopic(topic string) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+	t.deleted = append(t.deleted, topic)
+	return nil
+}
+
+func (t *loopbackTransport) Close() error {
+	return nil
+}
+
+func TestRequest(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	bus.Handle("order.get", func(ctx context.Context, req *Event) (interface{}, error) {
+		var payload map[string]int
+		req.Decode(&payload)
+		if payload["id"] == 0 {
+			return nil, errors.New("订单不存在")
+		}
+		return map[string]int{"id": payload["id"], "amount": 10}, nil
+	})
+
+	resp, err := bus.Request(context.Background(), "order.get", map[string]int{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order map[string]int
+	if err := resp.Decode(&order); err != nil || order["amount"] != 10 {
+		t.Errorf("响应不正确:%v %v", order, err)
+	}
+
+	_, err = bus.Request(context.Background(), "order.get", map[string]int{})
+	var replyErr *ReplyError
+	if !errors.As(err, &replyErr) || replyErr.Message != "订单不存在" {
+		t.Errorf("处理方返回错误时应返回ReplyError:%v", err)
+	}
+	if _, err := bus.Request(context.Background(), "order.none", nil); !errors.Is(err, ErrNoResponder) {
+		t.Errorf("没有处理方:%v", err)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	// 普通订阅者不发送响应
+	bus.Subscribe("order.get", func(ctx context.Context, e *Event) error {
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := bus.Request(ctx, "order.get", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("应返回超时错误:%v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("超时后应立即返回:%s", elapsed)
+	}
+	if len(bus.requests.pending) != 0 {
+		t.Error("超时后应删除等待的请求")
+	}
+}
+
+func TestCloseReplies(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	transport := newLoopbackTransport(bus)
+	bus.transport = transport
+	bus.Handle("order.get", func(ctx context.Context, req *Event) (interface{}, error) {
+		return "ok", nil
+	})
+	if _, err := bus.Request(context.Background(), "order.get", nil); err != nil {
+		t.Fatal(err)
+	}
+	topic := bus.requests.topic
+	if !transport.topics[topic] {
+		t.Fatal("请求时应订阅响应主题")
+	}
+
+	// 重新初始化后响应主题迁移到新实例，关闭旧实例时不删除
+	next := &Instance{transport: transport}
+	if err := next.Init(types.EventConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	transport.bus = next
+	bus.Close()
+	if len(transport.deleted) != 0 || next.requests.sub == nil {
+		t.Fatalf("响应主题已迁移到新实例，不应删除:%v", transport.deleted)
+	}
+
+	next.Close()
+	if transport.topics[topic] || strings.Join(transport.deleted, ",") != topic || next.requests.sub != nil {
+		t.Errorf("关闭时应取消订阅并删除响应主题:%v %v", transport.topics, transport.deleted)
+	}
+}
+
+func TestReplyMetricTopic(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	bus.UsePublish(MetricsPublishInterceptor())
+	bus.Handle("order.get", func(ctx context.Context, req *Event) (interface{}, error) {
+		return "ok", nil
+	})
+	counter := types.EventPublishCounter.WithLabelValues(replyMetricTopic, "success")
+	before := testutil.ToFloat64(counter)
+	for idx := 0; idx < 2; idx++ {
+		if _, err := bus.Request(context.Background(), "order.get", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 2 {
+		t.Errorf("响应主题的发布次数:%v", got)
+	}
+	if metricTopic(bus.requests.topic) != replyMetricTopic || metricTopic("order.get") != "order.get" {
+		t.Error("响应主题应统计为同一个标签")
+	}
+}
+
+func TestRequestRedis(t *testing.T) {
+	for _, transport := range []string{TransportRedisPubSub, TransportRedisStream} {
+		t.Run(transport, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			bus := newTestBus(t, types.EventConfig{
+				RedisConfig: types.RedisConfig{Addr: mr.Addr()},
+				Transport:   transport,
+				EventLog:    EventLogFile,
+				EventLogDir: t.TempDir(),
+			})
+			_, err := bus.Handle("order.get", func(ctx context.Context, req *Event) (interface{}, error) {
+				var payload map[string]int
+				req.Decode(&payload)
+				return map[string]int{"id": payload["id"], "amount": 10}, nil
+			}, WithGroup("order-service"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := bus.replies()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if transport == TransportRedisPubSub {
+				// 发布订阅的订阅是异步生效的
+				channels := []string{DefaultStreamPrefix + "order.get", DefaultStreamPrefix + r.topic}
+				waitFor(t, "订阅未生效", func() bool {
+					subs := mr.PubSubNumSub(channels...)
+					return subs[channels[0]] == 1 && subs[channels[1]] == 1
+				})
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			resp, err := bus.Request(ctx, "order.get", map[string]int{"id": 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var order map[string]int
+			if err := resp.Decode(&order); err != nil || order["id"] != 1 || order["amount"] != 10 {
+				t.Errorf("响应不正确:%v %v", order, err)
+			}
+
+			// 事件日志只记录请求，不记录响应
+			list, err := bus.EventLog().Read(1, 10)
+			if err != nil || len(list) != 1 || list[0].Topic != "order.get" {
+				t.Errorf("事件日志不应记录响应:%v %v", list, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hoorayui/core-framework/components/log"
+)
+
+// ErrDeadLettered 事件重试耗尽后已写入死信存储
+var ErrDeadLettered = errors.New("事件已写入死信")
+
+// RetryPolicy 重试策略，重试间隔按指数增长
+type RetryPolicy struct {
+	MaxAttempts     int           // 最大执行次数，包含首次执行
+	InitialInterval time.Duration // 首次重试间隔，默认100ms
+	MaxInterval     time.Duration // 最大重试间隔，默认10s
+	Multiplier      float64       // 间隔增长倍数，默认2
+}
+
+// WithRetry 设置订阅者的重试策略
+func WithRetry(policy RetryPolicy) SubscribeOption {
+	return func(s *Subscription) {
+		if policy.InitialInterval <= 0 {
+			policy.InitialInterval = 100 * time.Millisecond
+		}
+		if policy.MaxInterval <= 0 {
+			policy.MaxInterval = 10 * time.Second
+		}
+		if policy.Multiplier < 1 {
+			policy.Multiplier = 2
+		}
+		s.retry = &policy
+	}
+}
+
+// WithName 设置订阅者名称，死信重放时只投递给同名订阅者，未设置名称时只能在原进程中重放
+func WithName(name string) SubscribeOption {
+	return func(s *Subscription) {
+		s.name = name
+	}
+}
+
+// identity 死信中记录的订阅者，未命名时使用进程标识和订阅者编号
+func (s *Subscription) identity() string {
+	if s.name != "" {
+		return s.name
+	}
+	return fmt.Sprintf("%s#%d", processID, s.id)
+}
+
+// backoff 第attempt次失败后的等待时间
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	interval := float64(p.InitialInterval)
+	for n := 1; n < attempt; n++ {
+		interval *= p.Multiplier
+		if interval >= float64(p.MaxInterval) {
+			return p.MaxInterval
+		}
+	}
+	return time.Duration(interval)
+}
+
+// invoke 执行订阅者，失败时按重试策略重试，重试耗尽后写入死信存储
+func (i *Instance) invoke(ctx context.Context, sub *Subscription, e *Event) error {
+	attempts := 1
+	if sub.retry != nil && sub.retry.MaxAttempts > 1 {
+		attempts = sub.retry.MaxAttempts
+	}
+	handler := i.consumeChain(sub.handler)
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = handler(ctx, e); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		log.Errorf("事件[%s]订阅者[%d]第%d次执行失败:%s", e.Topic, sub.id, attempt, err.Error())
+		select {
+		case <-time.After(sub.retry.backoff(attempt)):
+		case <-ctx.Done():
+			return fmt.Errorf("%w，重试已取消:%s", err, ctx.Err().Error())
+		}
+	}
+	if i.deadLetter == nil {
+		return err
+	}
+	d := newDeadLetter(e, sub, err, attempts)
+	if saveErr := i.deadLetter.Save(d); saveErr != nil {
+		log.Errorf("事件[%s]写入死信失败:%s", e.Topic, saveErr.Error())
+		return err
+	}
+	return fmt.Errorf("%w[%s]:%w", ErrDeadLettered, d.ID, err)
+}
+
+// allDeadLettered 判断deliver返回的错误是否都已写入死信
+func allDeadLettered(err error) bool {
+	if err == nil {
+		return true
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if !errors.Is(e, ErrDeadLettered) {
+				return false
+			}
+		}
+		return true
+	}
+	return errors.Is(err, ErrDeadLettered)
+}
@@ -0,0 +1,160 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hoorayui/core-framework/types"
+)
+
+// memoryDeadLetterStore 测试用的死信存储
+type memoryDeadLetterStore struct {
+	l       sync.Mutex
+	letters map[string]*DeadLetter
+}
+
+func newMemoryDeadLetterStore() *memoryDeadLetterStore {
+	return &memoryDeadLetterStore{letters: map[string]*DeadLetter{}}
+}
+
+func (s *memoryDeadLetterStore) Save(d *DeadLetter) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	copied := *d
+	s.letters[d.ID] = &copied
+	return nil
+}
+
+func (s *memoryDeadLetterStore) List(topic string, offset, limit int) ([]*DeadLetter, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	list := []*DeadLetter{}
+	for _, d := range s.letters {
+		if topic == "" || d.Topic == topic {
+			list = append(list, d)
+		}
+	}
+	return list, nil
+}
+
+func (s *memoryDeadLetterStore) Get(id string) (*DeadLetter, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	d, ok := s.letters[id]
+	if !ok {
+		return nil, ErrDeadLetterNotFound
+	}
+	copied := *d
+	return &copied, nil
+}
+
+func (s *memoryDeadLetterStore) Delete(id string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	delete(s.letters, id)
+	return nil
+}
+
+func TestBackoff(t *testing.T) {
+	p := &RetryPolicy{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 10: time.Second} {
+		if got := p.backoff(attempt); got != want {
+			t.Errorf("第%d次失败:期望%s，实际%s", attempt, want, got)
+		}
+	}
+}
+
+// countingHandler 记录执行次数，fail为true时返回错误
+func countingHandler(count *int32, fail *int32) Handler {
+	return func(ctx context.Context, e *Event) error {
+		atomic.AddInt32(count, 1)
+		if atomic.LoadInt32(fail) == 1 {
+			return errors.New("处理失败")
+		}
+		return nil
+	}
+}
+
+func TestRetryDeadLetter(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	store := newMemoryDeadLetterStore()
+	bus.SetDeadLetterStore(store)
+	retry := WithRetry(RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond})
+
+	var named, other, unnamed, unnamedOther int32
+	fail, ok := int32(1), int32(0)
+	bus.Subscribe("order.created", countingHandler(&named, &fail), retry, WithName("named"))
+	bus.Subscribe("order.created", countingHandler(&other, &ok), WithName("other"))
+	bus.Subscribe("order.created", countingHandler(&unnamed, &fail), retry)
+	bus.Subscribe("order.created", countingHandler(&unnamedOther, &ok))
+
+	err := bus.Publish(context.Background(), "order.created", map[string]int{"id": 1}, WithKey("1"))
+	if !errors.Is(err, ErrDeadLettered) {
+		t.Fatalf("重试耗尽后应写入死信:%v", err)
+	}
+	if named != 3 || unnamed != 3 || other != 1 || unnamedOther != 1 {
+		t.Fatalf("执行次数不正确:%d %d %d %d", named, unnamed, other, unnamedOther)
+	}
+	letters, _ := store.List("order.created", 0, 10)
+	if len(letters) != 2 {
+		t.Fatalf("死信数量:%d", len(letters))
+	}
+	bySubscriber := map[string]*DeadLetter{}
+	for _, d := range letters {
+		bySubscriber[d.Subscriber] = d
+		if d.Attempts != 3 || d.Key != "1" || d.Payload != `{"id":1}` {
+			t.Errorf("死信内容不正确:%+v", d)
+		}
+	}
+	if bySubscriber["named"] == nil {
+		t.Fatalf("死信未记录订阅者名称:%+v", letters)
+	}
+	delete(bySubscriber, "named")
+
+	// 重放失败时更新死信，只投递给处理失败的订阅者
+	for subscriber, d := range bySubscriber {
+		if err := bus.Replay(context.Background(), d.ID); err == nil {
+			t.Fatalf("订阅者[%s]仍然失败时重放应返回错误", subscriber)
+		}
+		if updated, _ := store.Get(d.ID); updated.Attempts != 4 {
+			t.Errorf("重放失败后执行次数:%d", updated.Attempts)
+		}
+	}
+	if unnamed != 4 || unnamedOther != 1 || named != 3 || other != 1 {
+		t.Fatalf("未命名的订阅者只应重放给原订阅者:%d %d %d %d", named, unnamed, other, unnamedOther)
+	}
+
+	// 重放成功后删除死信
+	atomic.StoreInt32(&fail, 0)
+	for _, d := range letters {
+		if err := bus.Replay(context.Background(), d.ID); err != nil {
+			t.Fatalf("重放失败:%s", err)
+		}
+	}
+	if named != 4 || unnamed != 5 || other != 1 || unnamedOther != 1 {
+		t.Errorf("重放只应投递给处理失败的订阅者:%d %d %d %d", named, unnamed, other, unnamedOther)
+	}
+	if letters, _ := store.List("", 0, 10); len(letters) != 0 {
+		t.Errorf("重放成功后应删除死信:%d", len(letters))
+	}
+	if err := bus.Replay(context.Background(), "not-exist"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("死信不存在:%v", err)
+	}
+}
+
+func TestReplayUnnamedFromOtherProcess(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	store := newMemoryDeadLetterStore()
+	bus.SetDeadLetterStore(store)
+	var count, ok int32
+	bus.Subscribe("order.created", countingHandler(&count, &ok))
+	store.Save(&DeadLetter{ID: "d1", Topic: "order.created", Subscriber: "other-process#1", Payload: "{}"})
+
+	if err := bus.Replay(context.Background(), "d1"); err == nil || count != 0 {
+		t.Errorf("其他进程的未命名订阅者的死信不应重放:%v %d", err, count)
+	}
+}
@@ -0,0 +1,56 @@
+package event
+
+import "strings"
+
+// 主题以"."分隔层级，订阅时支持通配符
+const (
+	WildcardOne  = "*" // 匹配一个层级，如order.*.created
+	WildcardRest = ">" // 匹配之后的一个或多个层级，只能位于末尾，如order.>
+)
+
+// IsWildcard 判断主题是否包含通配符
+func IsWildcard(topic string) bool {
+	for _, token := range strings.Split(topic, ".") {
+		if token == WildcardOne || token == WildcardRest {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchTopic 判断主题是否匹配订阅的主题
+func MatchTopic(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	patterns, tokens := strings.Split(pattern, "."), strings.Split(topic, ".")
+	for idx, p := range patterns {
+		if p == WildcardRest && idx == len(patterns)-1 {
+			return len(tokens) > idx
+		}
+		if idx >= len(tokens) {
+			return false
+		}
+		if p != WildcardOne && p != tokens[idx] {
+			return false
+		}
+	}
+	return len(patterns) == len(tokens)
+}
+
+// match 查找主题的所有订阅者，包含通配符订阅
+func (i *Instance) match(topic string) []*Subscription {
+	i.l.RLock()
+	defer i.l.RUnlock()
+	subs := i.watcher[topic]
+	if len(i.wildcards) == 0 {
+		return subs
+	}
+	subs = append([]*Subscription{}, subs...)
+	for pattern := range i.wildcards {
+		if MatchTopic(pattern, topic) {
+			subs = append(subs, i.watcher[pattern]...)
+		}
+	}
+	return subs
+}
@@ -0,0 +1,77 @@
+package event
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hoorayui/core-framework/types"
+)
+
+func TestMatchTopic(t *testing.T) {
+	cases := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"order.created", "order.created", true},
+		{"order.*", "order.created", true},
+		{"order.*", "order.created.v2", false},
+		{"order.*", "order", false},
+		{"*.created", "user.created", true},
+		{"order.*.paid", "order.1.paid", true},
+		{"order.*.paid", "order.1.refunded", false},
+		{"order.>", "order.created", true},
+		{"order.>", "order.created.v2", true},
+		{"order.>", "order", false},
+		{">", "order.created", true},
+		{"order.>.paid", "order.1.paid", false},
+	}
+	for _, c := range cases {
+		if got := MatchTopic(c.pattern, c.topic); got != c.want {
+			t.Errorf("%s匹配%s:期望%v，实际%v", c.pattern, c.topic, c.want, got)
+		}
+	}
+	if IsWildcard("order.created") || !IsWildcard("order.*") || !IsWildcard("order.>") || IsWildcard("order.*x") {
+		t.Error("通配符判断不正确")
+	}
+}
+
+func TestWildcardSubscribe(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{NoSubscriberMetric: true})
+	var l sync.Mutex
+	received := map[string][]string{}
+	record := func(name string) Handler {
+		return func(ctx context.Context, e *Event) error {
+			l.Lock()
+			defer l.Unlock()
+			received[e.Topic] = append(received[e.Topic], name)
+			return nil
+		}
+	}
+	bus.Subscribe("order.created", record("exact"))
+	bus.Subscribe("order.*", record("one"))
+	rest, _ := bus.Subscribe("order.>", record("rest"))
+
+	for _, topic := range []string{"order.created", "order.1.paid", "user.created"} {
+		if err := bus.Publish(context.Background(), topic, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for topic, want := range map[string]string{"order.created": "exact,one,rest", "order.1.paid": "rest", "user.created": ""} {
+		got := received[topic]
+		sort.Strings(got)
+		if strings.Join(got, ",") != want {
+			t.Errorf("%s:期望%s，实际%v", topic, want, got)
+		}
+	}
+
+	rest.Unsubscribe()
+	if _, ok := bus.wildcards["order.>"]; ok {
+		t.Error("取消订阅后应删除通配符")
+	}
+	if len(bus.match("order.1.paid")) != 0 {
+		t.Error("取消订阅后不应再匹配")
+	}
+}
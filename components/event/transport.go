@@ -0,0 +1,117 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hoorayui/core-framework/components/log"
+)
+
+// 传输层类型
+const (
+	TransportRedisPubSub = "redis_pubsub" // redis发布订阅，广播，至多一次
+	TransportRedisStream = "redis_stream" // redis stream，支持消费组，至少一次
+)
+
+// Transport 跨进程传输事件
+type Transport interface {
+	Name() string
+	Publish(ctx context.Context, e *Event) error
+	Subscribe(topic, group string) error   // 开始接收主题事件，group为空时广播
+	Unsubscribe(topic, group string) error // 停止接收主题事件
+	Close() error
+}
+
+// TopicDeleter 保存了主题消息的传输层实现该接口，用于删除不再使用的主题，如进程的响应主题
+type TopicDeleter interface {
+	DeleteTopic(topic string) error
+}
+
+// Receiver 传输层收到事件后的回调，返回错误时支持重投的传输层会再次投递
+type Receiver func(ctx context.Context, e *Event, group string) error
+
+// envelope 事件序列化格式
+type envelope struct {
+	ID       string            `json:"id"`
+	Topic    string            `json:"topic"`
+	Key      string            `json:"key,omitempty"`
+	Payload  json.RawMessage   `json:"payload"`
+	Time     time.Time         `json:"time"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func encodeEvent(e *Event) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func decodeEvent(b []byte) (*Event, error) {
+	env := &envelope{}
+	if err := json.Unmarshal(b, env); err != nil {
+		return nil, err
+	}
+	return &Event{
+		ID:       env.ID,
+		Topic:    env.Topic,
+		Key:      env.Key,
+		Payload:  env.Payload,
+		Time:     env.Time,
+		Metadata: env.Metadata,
+	}, nil
+}
+
+func remoteKey(topic, group string) string {
+	return topic + "\x00" + group
+}
+
+// remoteSubscribe 第一个订阅者出现时向传输层订阅，调用方需持有写锁
+func (i *Instance) remoteSubscribe(sub *Subscription) error {
+	if i.transport == nil {
+		return nil
+	}
+	key := remoteKey(sub.topic, sub.group)
+	if i.remoteRefs[key] > 0 {
+		i.remoteRefs[key]++
+		return nil
+	}
+	if err := i.transport.Subscribe(sub.topic, sub.group); err != nil {
+		return fmt.Errorf("事件[%s]订阅传输层[%s]失败:%w", sub.topic, i.transport.Name(), err)
+	}
+	i.remoteRefs[key] = 1
+	return nil
+}
+
+// remoteUnsubscribe 最后一个订阅者取消时向传输层取消订阅，调用方需持有写锁
+func (i *Instance) remoteUnsubscribe(sub *Subscription) {
+	if i.transport == nil {
+		return
+	}
+	key := remoteKey(sub.topic, sub.group)
+	i.remoteRefs[key]--
+	if i.remoteRefs[key] > 0 {
+		return
+	}
+	delete(i.remoteRefs, key)
+	if err := i.transport.Unsubscribe(sub.topic, sub.group); err != nil {
+		log.Errorf("事件[%s]取消订阅传输层[%s]失败:%s", sub.topic, i.transport.Name(), err.Error())
+	}
+}
+
+// receive 将传输层收到的事件分发给本进程对应消费组的订阅者
+func (i *Instance) receive(ctx context.Context, e *Event, group string) error {
+	var subs []*Subscription
+	for _, sub := range i.match(e.Topic) {
+		if sub.group == group {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+	// 已写入死信的事件视为处理完成，不再由传输层重投
+	if err := i.deliver(ctx, e, subs); !allDeadLettered(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+	"github.com/hoorayui/core-framework/components/log"
+)
+
+// DefaultStreamPrefix 默认的redis key前缀
+const DefaultStreamPrefix = "event:"
+
+// DefaultStreamMaxLen 每个stream默认保留的大约消息数，超过后裁剪最早的消息
+const DefaultStreamMaxLen = 100000
+
+// RedisPubSubTransport 基于redis发布订阅，所有实例都会收到事件，不保证送达
+type RedisPubSubTransport struct {
+	client  *goredis.Client
+	prefix  string
+	receive Receiver
+
+	l      sync.Mutex
+	pubsub *goredis.PubSub
+	groups map[string]map[string]bool // topic -> groups
+}
+
+// NewRedisPubSubTransport 创建redis发布订阅传输层
+func NewRedisPubSubTransport(client *goredis.Client, prefix string, receive Receiver) *RedisPubSubTransport {
+	return &RedisPubSubTransport{
+		client:  client,
+		prefix:  prefix,
+		receive: receive,
+		groups:  map[string]map[string]bool{},
+	}
+}
+
+// Name 传输层名称
+func (t *RedisPubSubTransport) Name() string {
+	return TransportRedisPubSub
+}
+
+// Publish 发布事件
+func (t *RedisPubSubTransport) Publish(ctx context.Context, e *Event) error {
+	b, err := encodeEvent(e)
+	if err != nil {
+		return err
+	}
+	return t.client.Publish(t.prefix+e.Topic, b).Err()
+}
+
+// Subscribe 订阅主题，发布订阅不支持竞争消费，消费组按广播处理
+func (t *RedisPubSubTransport) Subscribe(topic, group string) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+	if group != "" {
+		log.Infof("传输层[%s]不支持消费组，事件[%s]的消费组[%s]按广播处理", t.Name(), topic, group)
+	}
+	if groups, ok := t.groups[topic]; ok {
+		groups[group] = true
+		return nil
+	}
+	t.groups[topic] = map[string]bool{group: true}
+	if t.pubsub == nil {
+		t.pubsub = t.client.Subscribe()
+		go t.loop(t.pubsub.Channel())
+	}
+	if IsWildcard(topic) {
+		return t.pubsub.PSubscribe(t.channelPattern(topic))
+	}
+	return t.pubsub.Subscribe(t.prefix + topic)
+}
+
+// channelPattern 将通配符主题转换为redis的频道模式，匹配范围更宽，由订阅方再按主题过滤
+func (t *RedisPubSubTransport) channelPattern(topic string) string {
+	tokens := strings.Split(topic, ".")
+	for idx, token := range tokens {
+		if token == WildcardOne || token == WildcardRest {
+			tokens[idx] = "*"
+		}
+	}
+	return t.prefix + strings.Join(tokens, ".")
+}
+
+// Unsubscribe 取消订阅
+func (t *RedisPubSubTransport) Unsubscribe(topic, group string) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+	groups, ok := t.groups[topic]
+	if !ok {
+		return nil
+	}
+	delete(groups, group)
+	if len(groups) > 0 {
+		return nil
+	}
+	delete(t.groups, topic)
+	if IsWildcard(topic) {
+		return t.pubsub.PUnsubscribe(t.channelPattern(topic))
+	}
+	return t.pubsub.Unsubscribe(t.prefix + topic)
+}
+
+func (t *RedisPubSubTransport) loop(ch <-chan *goredis.Message) {
+	for msg := range ch {
+		e, err := decodeEvent([]byte(msg.Payload))
+		if err != nil {
+			log.Errorf("解析事件[%s]失败:%s", msg.Channel, err.Error())
+			continue
+		}
+		// 同一事件同时匹配频道和多个模式时redis会收到多条消息，
+		// 每个消费组只处理其中一个来源的消息，取匹配的频道或模式中最小的
+		source := msg.Channel
+		if msg.Pattern != "" {
+			source = msg.Pattern
+		}
+		t.l.Lock()
+		owners := map[string]string{}
+		for topic, topicGroups := range t.groups {
+			if !MatchTopic(topic, e.Topic) {
+				continue
+			}
+			from := t.prefix + topic
+			if IsWildcard(topic) {
+				from = t.channelPattern(topic)
+			}
+			for group := range topicGroups {
+				if owner, ok := owners[group]; !ok || from < owner {
+					owners[group] = from
+				}
+			}
+		}
+		t.l.Unlock()
+		for group, owner := range owners {
+			if owner == source {
+				t.receive(context.Background(), e, group)
+			}
+		}
+	}
+}
+
+// Close 关闭，之后重新订阅时创建新的连接
+func (t *RedisPubSubTransport) Close() error {
+	t.l.Lock()
+	defer t.l.Unlock()
+	if t.pubsub == nil {
+		return nil
+	}
+	err := t.pubsub.Close()
+	t.pubsub = nil
+	t.groups = map[string]map[string]bool{}
+	return err
+}
+
+// RedisStreamTransport 基于redis stream，消费组内竞争消费，处理成功后确认，保证至少一次送达
+type RedisStreamTransport struct {
+	client   *goredis.Client
+	prefix   string
+	consumer string
+	maxLen   int64
+	receive  Receiver
+
+	// ClaimIdle 其他消费者未确认的消息空闲超过该时间后由当前消费者接管
+	ClaimIdle time.Duration
+	// RetryInterval 处理失败的消息重新投递的间隔
+	RetryInterval time.Duration
+
+	l     sync.Mutex
+	loops map[string]chan struct{}
+	wg    sync.WaitGroup
+}
+
+// replyStreamTTL 响应stream的过期时间，每次响应后重置，进程异常退出未删除时由redis清理
+const replyStreamTTL = 10 * time.Minute
+
+// NewRedisStreamTransport 创建redis stream传输层，consumer为空时使用主机名和进程号，
+// maxLen不大于0时使用DefaultStreamMaxLen
+func NewRedisStreamTransport(client *goredis.Client, prefix, consumer string, maxLen int64, receive Receiver) *RedisStreamTransport {
+	if consumer == "" {
+		host, _ := os.Hostname()
+		consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if maxLen <= 0 {
+		maxLen = DefaultStreamMaxLen
+	}
+	return &RedisStreamTransport{
+		client:        client,
+		prefix:        prefix,
+		consumer:      consumer,
+		maxLen:        maxLen,
+		receive:       receive,
+		ClaimIdle:     time.Minute,
+		RetryInterval: 5 * time.Second,
+		loops:         map[string]chan struct{}{},
+	}
+}
+
+// Name 传输层名称
+func (t *RedisStreamTransport) Name() string {
+	return TransportRedisStream
+}
+
+// Publish 发布事件
+func (t *RedisStreamTransport) Publish(ctx context.Context, e *Event) error {
+	b, err := encodeEvent(e)
+	if err != nil {
+		return err
+	}
+	args := &goredis.XAddArgs{
+		Stream:       t.prefix + e.Topic,
+		MaxLenApprox: t.maxLen,
+		ID:           "*",
+		Values:       map[string]interface{}{"event": string(b)},
+	}
+	if !strings.HasPrefix(e.Topic, ReplyTopicPrefix) {
+		return t.client.XAdd(args).Err()
+	}
+	pipe := t.client.TxPipeline()
+	pipe.XAdd(args)
+	pipe.Expire(args.Stream, replyStreamTTL)
+	_, err = pipe.Exec()
+	return err
+}
+
+// DeleteTopic 删除主题的stream
+func (t *RedisStreamTransport) DeleteTopic(topic string) error {
+	return t.client.Del(t.prefix + topic).Err()
+}
+
+// Subscribe 订阅主题，每个主题对应一个stream，不支持通配符
+func (t *RedisStreamTransport) Subscribe(topic, group string) error {
+	if IsWildcard(topic) {
+		return fmt.Errorf("传输层[%s]不支持通配符订阅[%s]", t.Name(), topic)
+	}
+	stream := t.prefix + topic
+	if group != "" {
+		err := t.client.XGroupCreateMkStream(stream, group, "$").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return err
+		}
+	}
+	t.l.Lock()
+	defer t.l.Unlock()
+	key := remoteKey(topic, group)
+	if _, ok := t.loops[key]; ok {
+		return nil
+	}
+	stop := make(chan struct{})
+	t.loops[key] = stop
+	t.wg.Add(1)
+	if group == "" {
+		// 订阅返回前确定起始位置，避免丢失订阅后立即发布的事件
+		lastID := "0-0"
+		if msgs, err := t.client.XRevRangeN(stream, "+", "-", 1).Result(); err == nil && len(msgs) > 0 {
+			lastID = msgs[0].ID
+		}
+		go t.fanout(stream, lastID, stop)
+	} else {
+		go t.consume(stream, group, stop)
+	}
+	return nil
+}
+
+// Unsubscribe 取消订阅
+func (t *RedisStreamTransport) Unsubscribe(topic, group string) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+	key := remoteKey(topic, group)
+	if stop, ok := t.loops[key]; ok {
+		close(stop)
+		delete(t.loops, key)
+	}
+	return nil
+}
+
+// fanout 广播模式，从订阅时的最新位置开始读取
+func (t *RedisStreamTransport) fanout(stream, lastID string, stop chan struct{}) {
+	defer t.wg.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		streams, err := t.client.XRead(&goredis.XReadArgs{
+			Streams: []string{stream, lastID},
+			Count:   100,
+			Block:   time.Second,
+		}).Result()
+		if err != nil {
+			if err != goredis.Nil {
+				log.Errorf("读取事件流[%s]失败:%s", stream, err.Error())
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				lastID = msg.ID
+				t.handle(stream, "", msg)
+			}
+		}
+	}
+}
+
+// consume 消费组模式，处理成功后确认，失败的消息定期重新投递
+func (t *RedisStreamTransport) consume(stream, group string, stop chan struct{}) {
+	defer t.wg.Done()
+	lastRetry := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if time.Since(lastRetry) >= t.RetryInterval {
+			lastRetry = time.Now()
+			t.retryPending(stream, group)
+		}
+		streams, err := t.client.XReadGroup(&goredis.XReadGroupArgs{
+			Group:    group,
+			Consumer: t.consumer,
+			Streams:  []string{stream, ">"},
+			Count:    100,
+			Block:    time.Second,
+		}).Result()
+		if err != nil {
+			if err != goredis.Nil {
+				log.Errorf("读取事件流[%s]消费组[%s]失败:%s", stream, group, err.Error())
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				t.handle(stream, group, msg)
+			}
+		}
+	}
+}
+
+// retryPending 重新处理当前消费者未确认的消息，并接管空闲超时的其他消费者的消息
+func (t *RedisStreamTransport) retryPending(stream, group string) {
+	streams, err := t.client.XReadGroup(&goredis.XReadGroupArgs{
+		Group:    group,
+		Consumer: t.consumer,
+		Streams:  []string{stream, "0"},
+		Count:    100,
+	}).Result()
+	if err == nil {
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				t.handle(stream, group, msg)
+			}
+		}
+	}
+
+	pending, err := t.client.XPendingExt(&goredis.XPendingExtArgs{
+		Stream: stream,
+		Group:  group,
+		Start:  "-",
+		End:    "+",
+		Count:  100,
+	}).Result()
+	if err != nil {
+		return
+	}
+	var ids []string
+	for _, p := range pending {
+		if p.Consumer != t.consumer && p.Idle >= t.ClaimIdle {
+			ids = append(ids, p.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	msgs, err := t.client.XClaim(&goredis.XClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: t.consumer,
+		MinIdle:  t.ClaimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		log.Errorf("接管事件流[%s]消费组[%s]的消息失败:%s", stream, group, err.Error())
+		return
+	}
+	for _, msg := range msgs {
+		t.handle(stream, group, msg)
+	}
+}
+
+func (t *RedisStreamTransport) handle(stream, group string, msg goredis.XMessage) {
+	raw, _ := msg.Values["event"].(string)
+	e, err := decodeEvent([]byte(raw))
+	if err != nil {
+		// 无法解析的消息直接确认，避免反复投递
+		log.Errorf("解析事件流[%s]的消息[%s]失败:%s", stream, msg.ID, err.Error())
+		t.ack(stream, group, msg.ID)
+		return
+	}
+	if err := t.receive(context.Background(), e, group); err != nil {
+		return
+	}
+	t.ack(stream, group, msg.ID)
+}
+
+func (t *RedisStreamTransport) ack(stream, group, id string) {
+	if group == "" {
+		return
+	}
+	if err := t.client.XAck(stream, group, id).Err(); err != nil {
+		log.Errorf("确认事件流[%s]的消息[%s]失败:%s", stream, id, err.Error())
+	}
+}
+
+// Close 停止所有消费并等待退出
+func (t *RedisStreamTransport) Close() error {
+	t.l.Lock()
+	for key, stop := range t.loops {
+		close(stop)
+		delete(t.loops, key)
+	}
+	t.l.Unlock()
+	t.wg.Wait()
+	return nil
+}
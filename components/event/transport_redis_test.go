@@ -0,0 +1,217 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis"
+)
+
+// recorder 记录传输层投递的事件，fail不为空时返回错误，不确认消息
+type recorder struct {
+	l      sync.Mutex
+	events []*Event
+	fail   error
+}
+
+func (r *recorder) receive(ctx context.Context, e *Event, group string) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.events = append(r.events, e)
+	return r.fail
+}
+
+func (r *recorder) count() int {
+	r.l.Lock()
+	defer r.l.Unlock()
+	return len(r.events)
+}
+
+func (r *recorder) setFail(err error) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.fail = err
+}
+
+// newRedisClient 连接miniredis
+func newRedisClient(t *testing.T) (*goredis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, mr
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newStreamTransport(t *testing.T, client *goredis.Client, consumer string, r *recorder) *RedisStreamTransport {
+	transport := NewRedisStreamTransport(client, DefaultStreamPrefix, consumer, 0, r.receive)
+	t.Cleanup(func() { transport.Close() })
+	return transport
+}
+
+func pendingCount(t *testing.T, client *goredis.Client, stream, group string) int64 {
+	t.Helper()
+	pending, err := client.XPending(stream, group).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pending.Count
+}
+
+func TestRedisStreamGroup(t *testing.T) {
+	client, _ := newRedisClient(t)
+	first, second := &recorder{}, &recorder{}
+	a := newStreamTransport(t, client, "a", first)
+	b := newStreamTransport(t, client, "b", second)
+	for _, transport := range []*RedisStreamTransport{a, b} {
+		if err := transport.Subscribe("order.created", "billing"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for n := 0; n < 20; n++ {
+		if err := a.Publish(context.Background(), &Event{ID: fmt.Sprint(n), Topic: "order.created"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "消费组未处理所有事件", func() bool { return first.count()+second.count() >= 20 })
+	a.Close()
+	b.Close()
+
+	// 同一消费组内竞争消费，每个事件只处理一次
+	seen := map[string]bool{}
+	for _, e := range append(first.events, second.events...) {
+		if seen[e.ID] {
+			t.Errorf("事件[%s]被重复处理", e.ID)
+		}
+		seen[e.ID] = true
+	}
+	if len(seen) != 20 {
+		t.Errorf("处理的事件数:%d", len(seen))
+	}
+	if n := pendingCount(t, client, DefaultStreamPrefix+"order.created", "billing"); n != 0 {
+		t.Errorf("处理成功后应确认消息，未确认%d条", n)
+	}
+}
+
+func TestRedisStreamRetryPending(t *testing.T) {
+	client, _ := newRedisClient(t)
+	stream := DefaultStreamPrefix + "order.created"
+	failed := &recorder{fail: errors.New("处理失败")}
+	a := newStreamTransport(t, client, "a", failed)
+	if err := a.Subscribe("order.created", "billing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Publish(context.Background(), &Event{ID: "1", Topic: "order.created"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "事件未投递", func() bool { return failed.count() == 1 })
+	a.Close()
+	if n := pendingCount(t, client, stream, "billing"); n != 1 {
+		t.Fatalf("处理失败时不应确认消息，未确认%d条", n)
+	}
+
+	// 当前消费者重新处理自己未确认的消息
+	a.retryPending(stream, "billing")
+	if failed.count() != 2 || pendingCount(t, client, stream, "billing") != 1 {
+		t.Fatalf("应重新投递当前消费者未确认的消息:%d", failed.count())
+	}
+
+	// 空闲未超时的消息不被其他消费者接管
+	other := &recorder{}
+	b := newStreamTransport(t, client, "b", other)
+	b.ClaimIdle = time.Hour
+	b.retryPending(stream, "billing")
+	if other.count() != 0 {
+		t.Fatal("空闲未超时的消息不应被接管")
+	}
+	b.ClaimIdle = 10 * time.Millisecond
+	time.Sleep(20 * time.Millisecond)
+	b.retryPending(stream, "billing")
+	if other.count() != 1 || other.events[0].ID != "1" {
+		t.Fatalf("空闲超时的消息应被其他消费者接管:%d", other.count())
+	}
+	if n := pendingCount(t, client, stream, "billing"); n != 0 {
+		t.Errorf("接管后处理成功应确认消息，未确认%d条", n)
+	}
+}
+
+func TestRedisStreamFanout(t *testing.T) {
+	client, _ := newRedisClient(t)
+	recorders := []*recorder{{}, {}}
+	for idx, r := range recorders {
+		if err := newStreamTransport(t, client, fmt.Sprint(idx), r).Subscribe("order.created", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	publisher := newStreamTransport(t, client, "publisher", &recorder{})
+	if err := publisher.Publish(context.Background(), &Event{ID: "1", Topic: "order.created"}); err != nil {
+		t.Fatal(err)
+	}
+	for idx, r := range recorders {
+		waitFor(t, fmt.Sprintf("实例[%d]未收到广播的事件", idx), func() bool { return r.count() == 1 })
+	}
+}
+
+func TestRedisPubSub(t *testing.T) {
+	client, mr := newRedisClient(t)
+	recorders := []*recorder{{}, {}}
+	var transports []*RedisPubSubTransport
+	for _, r := range recorders {
+		transport := NewRedisPubSubTransport(client, DefaultStreamPrefix, r.receive)
+		t.Cleanup(func() { transport.Close() })
+		transports = append(transports, transport)
+	}
+	// 同一事件同时匹配频道和模式时只投递一次
+	if err := transports[0].Subscribe("order.created", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := transports[0].Subscribe("order.*", ""); err != nil {
+		t.Fatal(err)
+	}
+	// 消费组按广播处理
+	if err := transports[1].Subscribe("order.created", "billing"); err != nil {
+		t.Fatal(err)
+	}
+	channel := DefaultStreamPrefix + "order.created"
+	waitFor(t, "订阅未生效", func() bool {
+		return mr.PubSubNumSub(channel)[channel] == 2 && mr.PubSubNumPat() == 1
+	})
+	if err := transports[0].Publish(context.Background(), &Event{ID: "1", Topic: "order.created"}); err != nil {
+		t.Fatal(err)
+	}
+	for idx, r := range recorders {
+		waitFor(t, fmt.Sprintf("实例[%d]未收到事件", idx), func() bool { return r.count() >= 1 })
+	}
+	time.Sleep(50 * time.Millisecond)
+	if recorders[0].count() != 1 {
+		t.Errorf("同时匹配频道和模式时应只投递一次，实际%d次", recorders[0].count())
+	}
+
+	// 关闭后重新订阅
+	transports[1].Close()
+	if transports[1].pubsub != nil {
+		t.Fatal("关闭后应重置连接")
+	}
+	if err := transports[1].Subscribe("order.created", ""); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "重新订阅未生效", func() bool { return mr.PubSubNumSub(channel)[channel] == 2 })
+	transports[0].Publish(context.Background(), &Event{ID: "2", Topic: "order.created"})
+	waitFor(t, "关闭后重新订阅未收到事件", func() bool { return recorders[1].count() == 2 })
+}
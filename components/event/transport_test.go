@@ -0,0 +1,27 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hoorayui/core-framework/types"
+)
+
+func TestRedisStreamWildcard(t *testing.T) {
+	bus := newTestBus(t, types.EventConfig{})
+	transport := NewRedisStreamTransport(nil, DefaultStreamPrefix, "test", 0, bus.receive)
+	if transport.maxLen != DefaultStreamMaxLen {
+		t.Errorf("未设置长度时应使用默认值:%d", transport.maxLen)
+	}
+	bus.transport = transport
+
+	sub, err := bus.Subscribe("order.*", func(ctx context.Context, e *Event) error {
+		return nil
+	})
+	if err == nil || sub != nil {
+		t.Fatal("redis stream不支持通配符订阅，应返回错误")
+	}
+	if len(bus.match("order.created")) != 0 || len(bus.remoteRefs) != 0 {
+		t.Error("订阅失败时不应保留订阅者")
+	}
+}
@@ -0,0 +1,81 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrSchemaMismatch 事件payload与主题定义的类型不一致
+var ErrSchemaMismatch = errors.New("事件payload类型不匹配")
+
+// definitions 已定义的主题及payload类型
+var definitions sync.Map
+
+// Topic 类型安全的事件主题
+type Topic[T any] struct {
+	name string
+}
+
+// Define 定义主题，同一主题名定义为不同类型时panic，例如:
+//
+//	var OrderCreated = event.Define[OrderCreated]("order.created")
+func Define[T any](name string) Topic[T] {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if prev, loaded := definitions.LoadOrStore(name, typ); loaded && prev != typ {
+		panic(fmt.Sprintf("事件主题[%s]已定义为[%s]，不能重复定义为[%s]", name, prev, typ))
+	}
+	return Topic[T]{name: name}
+}
+
+// Name 主题名
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// Publish 发布事件
+func (t Topic[T]) Publish(ctx context.Context, payload T, opts ...PublishOption) error {
+	return GetInstance().Publish(ctx, t.name, payload, opts...)
+}
+
+// Subscribe 订阅事件
+func (t Topic[T]) Subscribe(handler func(ctx context.Context, payload T) error, opts ...SubscribeOption) (*Subscription, error) {
+	return GetInstance().Subscribe(t.name, t.wrap(handler), opts...)
+}
+
+// SubscribeOnce 订阅事件，处理一次后自动取消订阅
+func (t Topic[T]) SubscribeOnce(handler func(ctx context.Context, payload T) error, opts ...SubscribeOption) (*Subscription, error) {
+	return GetInstance().SubscribeOnce(t.name, t.wrap(handler), opts...)
+}
+
+func (t Topic[T]) wrap(handler func(ctx context.Context, payload T) error) Handler {
+	return func(ctx context.Context, e *Event) error {
+		payload, err := t.Decode(e)
+		if err != nil {
+			return err
+		}
+		return handler(ctx, payload)
+	}
+}
+
+// Decode 解析事件payload。进程内事件校验类型，跨进程事件按json严格解析，出现未定义的字段时返回错误
+func (t Topic[T]) Decode(e *Event) (T, error) {
+	var payload T
+	switch p := e.Payload.(type) {
+	case T:
+		return p, nil
+	case json.RawMessage:
+		decoder := json.NewDecoder(bytes.NewReader(p))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			return payload, fmt.Errorf("%w:事件[%s]:%s", ErrSchemaMismatch, e.Topic, err.Error())
+		}
+		return payload, nil
+	default:
+		return payload, fmt.Errorf("%w:事件[%s]期望[%T]，实际[%T]", ErrSchemaMismatch, e.Topic, payload, e.Payload)
+	}
+}
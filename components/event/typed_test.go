@@ -0,0 +1,74 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hoorayui/core-framework/types"
+)
+
+type orderCreated struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+func TestDefine(t *testing.T) {
+	Define[orderCreated]("typed.define")
+	Define[orderCreated]("typed.define")
+	defer func() {
+		if recover() == nil {
+			t.Error("同一主题定义为不同类型时应panic")
+		}
+	}()
+	Define[string]("typed.define")
+}
+
+func TestTopicPublish(t *testing.T) {
+	newTestBus(t, types.EventConfig{})
+	topic := Define[orderCreated]("typed.order.created")
+	var got orderCreated
+	topic.Subscribe(func(ctx context.Context, payload orderCreated) error {
+		got = payload
+		return nil
+	})
+	if err := topic.Publish(context.Background(), orderCreated{ID: 1, Status: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 || got.Status != "new" {
+		t.Errorf("payload不正确:%+v", got)
+	}
+
+	// 绕过类型化的主题发布了其他类型
+	err := Publish(context.Background(), topic.Name(), map[string]int{"id": 2})
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("类型不一致时订阅者应返回ErrSchemaMismatch:%v", err)
+	}
+}
+
+func TestTopicDecode(t *testing.T) {
+	topic := Define[orderCreated]("typed.order.decode")
+	cases := []struct {
+		payload interface{}
+		err     bool
+	}{
+		{orderCreated{ID: 1}, false},
+		{json.RawMessage(`{"id":1,"status":"new"}`), false},
+		{json.RawMessage(`{"id":1,"amount":10}`), true},
+		{json.RawMessage(`{"id":"1"}`), true},
+		{&orderCreated{ID: 1}, true},
+	}
+	for _, c := range cases {
+		payload, err := topic.Decode(&Event{Topic: topic.Name(), Payload: c.payload})
+		if c.err {
+			if !errors.Is(err, ErrSchemaMismatch) {
+				t.Errorf("%v:应返回ErrSchemaMismatch，实际%v", c.payload, err)
+			}
+			continue
+		}
+		if err != nil || payload.ID != 1 {
+			t.Errorf("%v:解析失败%+v %v", c.payload, payload, err)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoorayui/core-framework/components/jwt"
+)
+
+// ClaimsKey 声明在gin上下文中的key
+const ClaimsKey = "jwt_claims"
+
+// tokenKey token在gin上下文中的key
+const tokenKey = "jwt_token"
+
+// ErrTokenMissing 请求中没有token
+var ErrTokenMissing = errors.New("请求中没有token")
+
+// Config 认证中间件配置
+type Config struct {
+	Header string // 读取token的请求头，默认Authorization
+	Scheme string // 请求头中token的前缀，默认Bearer，为空时不去除
+	Cookie string // 读取token的cookie，为空时不读取
+	Query  string // 读取token的查询参数，为空时不读取
+	// 可选认证，没有token时继续处理请求，token无效时仍然返回401
+	Optional bool
+	// 跳过认证的路径，以*结尾时按前缀匹配
+	Skip []string
+	// 校验token的实例，为nil时使用全局实例
+	Instance *jwt.Instance
+}
+
+// DefaultConfig 从Authorization请求头读取Bearer token
+func DefaultConfig() Config {
+	return Config{Header: "Authorization", Scheme: "Bearer"}
+}
+
+// Error 认证失败的响应
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Middleware 认证中间件，校验通过后将声明保存到gin上下文，通过UserID等函数读取
+func Middleware(config Config) gin.HandlerFunc {
+	if config.Header == "" {
+		config.Header = "Authorization"
+	}
+	return func(c *gin.Context) {
+		if config.skip(c) {
+			c.Next()
+			return
+		}
+		token := config.extract(c)
+		if token == "" {
+			if config.Optional {
+				c.Next()
+				return
+			}
+			abort(c, ErrTokenMissing)
+			return
+		}
+		claims, err := config.parse(token)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.Set(ClaimsKey, claims)
+		c.Set(tokenKey, token)
+		c.Next()
+	}
+}
+
+// Optional 可选认证中间件，没有token时按匿名用户处理
+func Optional(config Config) gin.HandlerFunc {
+	config.Optional = true
+	return Middleware(config)
+}
+
+// skip 判断路径是否跳过认证，优先匹配注册的路由
+func (config Config) skip(c *gin.Context) bool {
+	for _, path := range config.Skip {
+		for _, p := range []string{c.FullPath(), c.Request.URL.Path} {
+			if strings.HasSuffix(path, "*") {
+				if strings.HasPrefix(p, strings.TrimSuffix(path, "*")) {
+					return true
+				}
+			} else if p == path {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extract 依次从请求头、cookie和查询参数读取token
+func (config Config) extract(c *gin.Context) string {
+	if v := c.GetHeader(config.Header); v != "" {
+		if config.Scheme == "" {
+			return v
+		}
+		prefix := config.Scheme + " "
+		if len(v) > len(prefix) && strings.EqualFold(v[:len(prefix)], prefix) {
+			return strings.TrimSpace(v[len(prefix):])
+		}
+	}
+	if config.Cookie != "" {
+		if v, err := c.Cookie(config.Cookie); err == nil && v != "" {
+			return v
+		}
+	}
+	if config.Query != "" {
+		return c.Query(config.Query)
+	}
+	return ""
+}
+
+func (config Config) parse(token string) (*jwt.Claims, error) {
+	i := config.Instance
+	if i == nil {
+		i = jwt.GetInstance()
+	}
+	if i == nil {
+		return nil, jwt.ErrNotInitialized
+	}
+	return i.ParseToken(token)
+}
+
+// abort 按错误返回对应的状态码，响应格式为{"error": "..."}
+func abort(c *gin.Context, err error) {
+	code := jwt.StatusCode(err)
+	if code == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	c.AbortWithStatusJSON(code, Error{Error: err.Error()})
+}
+
+// Claims 当前请求的声明，未认证时返回false，c可以是*gin.Context
+func Claims(c context.Context) (*jwt.Claims, bool) {
+	claims, ok := c.Value(ClaimsKey).(*jwt.Claims)
+	return claims, ok
+}
+
+// Authenticated 当前请求是否已认证
+func Authenticated(c context.Context) bool {
+	_, ok := Claims(c)
+	return ok
+}
+
+// UserID 当前用户ID，未认证时为0
+func UserID(c context.Context) int64 {
+	if claims, ok := Claims(c); ok {
+		return claims.UserID
+	}
+	return 0
+}
+
+// Username 当前用户名，未认证时为空
+func Username(c context.Context) string {
+	if claims, ok := Claims(c); ok {
+		return claims.Username
+	}
+	return ""
+}
+
+// SessionID 当前会话ID，未认证时为空
+func SessionID(c context.Context) string {
+	if claims, ok := Claims(c); ok {
+		return claims.SessionID
+	}
+	return ""
+}
+
+// Token 当前请求的原始token，用于撤销等操作
+func Token(c context.Context) string {
+	token, _ := c.Value(tokenKey).(string)
+	return token
+}
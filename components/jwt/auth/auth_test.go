@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoorayui/core-framework/components/jwt"
+	"github.com/hoorayui/core-framework/types"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	i, err := jwt.New(types.JWTConfig{Secret: "test-secret-0123456789abcdefghijklmnop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultConfig()
+	config.Cookie = "token"
+	config.Skip = []string{"/public/*"}
+	config.Instance = i
+	r := gin.New()
+	r.Use(Middleware(config))
+	handler := func(c *gin.Context) {
+		c.String(http.StatusOK, Username(c))
+	}
+	r.GET("/me", handler)
+	r.GET("/public/ping", handler)
+
+	token, _ := i.GenToken(1, "张三")
+	cases := []struct {
+		name   string
+		path   string
+		header string
+		cookie string
+		code   int
+		body   string
+	}{
+		{"请求头", "/me", "Bearer " + token, "", http.StatusOK, "张三"},
+		{"cookie", "/me", "", token, http.StatusOK, "张三"},
+		{"没有token", "/me", "", "", http.StatusUnauthorized, `{"error":"请求中没有token"}`},
+		{"token无效", "/me", "Bearer " + token + "x", "", http.StatusUnauthorized, ""},
+		{"跳过", "/public/ping", "", "", http.StatusOK, ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		if c.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "token", Value: c.cookie})
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.code || (c.body != "" && w.Body.String() != c.body) {
+			t.Fatalf("[%s]期望%d %s，实际%d %s", c.name, c.code, c.body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package jwt
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/hoorayui/core-framework/util"
+)
+
+// CustomClaims 自定义声明，嵌入RegisteredClaims或Claims即可实现，必须使用指针类型，例如
+//
+//	type MyClaims struct {
+//		jwt.Claims
+//		Roles    []string `json:"roles"`
+//		TenantID int64    `json:"tenant_id"`
+//	}
+//
+// 嵌入Claims时支持按用户和会话撤销，只嵌入RegisteredClaims时只支持撤销单个token
+type CustomClaims interface {
+	jwt.Claims
+	Registered() *jwt.StandardClaims
+}
+
+// sessionClaims 包含用户和会话的声明
+type sessionClaims interface {
+	session() (int64, string)
+}
+
+// RegisteredClaims 标准声明，不需要用户ID和用户名时嵌入
+type RegisteredClaims struct {
+	jwt.StandardClaims
+}
+
+// Registered 标准声明
+func (c *RegisteredClaims) Registered() *jwt.StandardClaims {
+	return &c.StandardClaims
+}
+
+// Issue 签发自定义声明的JWT，未设置的过期时间、签发时间、签发人、接收方和jti使用配置填充
+func (i *Instance) Issue(claims CustomClaims) (string, error) {
+	std := claims.Registered()
+	now := util.Now()
+	if std.ExpiresAt == 0 {
+		std.ExpiresAt = now.Add(i.Expire()).Unix()
+	}
+	if std.IssuedAt == 0 {
+		std.IssuedAt = now.Unix()
+	}
+	if std.Issuer == "" {
+		std.Issuer = i.config.Issuer
+	}
+	if std.Audience == "" {
+		std.Audience = i.config.Audience
+	}
+	if std.Id == "" {
+		std.Id = util.NewUUIDString("")
+	}
+	return i.sign(claims)
+}
+
+// ParseInto 解析JWT到自定义声明，校验签名、过期时间、签发人、接收方和是否撤销
+func (i *Instance) ParseInto(tokenString string, claims CustomClaims) error {
+	_, err := i.parseInto(tokenString, claims)
+	return err
+}
+
+// parseInto 签名正确时signed为true，此时声明已解析
+func (i *Instance) parseInto(tokenString string, claims CustomClaims) (signed bool, err error) {
+	// 声明由verify按配置的误差校验
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.ParseWithClaims(tokenString, claims, i.keyFunc)
+	if err != nil {
+		return false, convertError(err)
+	}
+	if !token.Valid { // 校验token
+		return false, ErrSignatureInvalid
+	}
+	if err := i.verify(claims.Registered()); err != nil {
+		return true, err
+	}
+	return true, i.checkRevoked(claims)
+}
+
+// Issue 使用全局实例签发自定义声明的JWT
+func Issue[C CustomClaims](claims C) (string, error) {
+	if instance == nil {
+		return "", ErrNotInitialized
+	}
+	return instance.Issue(claims)
+}
+
+// Parse 使用全局实例解析自定义声明，例如jwt.Parse[*MyClaims](token)
+func Parse[C CustomClaims](tokenString string) (C, error) {
+	return ParseWith[C](instance, tokenString)
+}
+
+// ParseWith 使用指定实例解析自定义声明
+func ParseWith[C CustomClaims](i *Instance, tokenString string) (C, error) {
+	var claims C
+	if i == nil {
+		return claims, ErrNotInitialized
+	}
+	t := reflect.TypeOf(claims)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return claims, fmt.Errorf("自定义声明[%v]必须为结构体指针", t)
+	}
+	parsed := reflect.New(t.Elem()).Interface().(C)
+	if err := i.ParseInto(tokenString, parsed); err != nil {
+		return claims, err
+	}
+	return parsed, nil
+}
@@ -0,0 +1,62 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// token校验错误，可通过errors.Is判断
+var (
+	ErrTokenMalformed        = errors.New("token格式不正确")
+	ErrAlgorithmNotAllowed   = errors.New("token签名算法不允许")
+	ErrUnknownKey            = errors.New("token签名密钥不存在")
+	ErrSignatureInvalid      = errors.New("token签名不正确")
+	ErrTokenExpired          = errors.New("token已过期")
+	ErrTokenNotValidYet      = errors.New("token尚未生效")
+	ErrTokenUsedBeforeIssued = errors.New("token签发时间晚于当前时间")
+	ErrInvalidIssuer         = errors.New("token签发人不正确")
+	ErrInvalidAudience       = errors.New("token接收方不正确")
+	ErrClaimMissing          = errors.New("token缺少必需的声明")
+	ErrTokenRevoked          = errors.New("token已撤销")
+)
+
+// convertError 将jwt库的错误转换为上面定义的错误
+func convertError(err error) error {
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+	if ve.Inner != nil {
+		for _, target := range []error{ErrAlgorithmNotAllowed, ErrUnknownKey} {
+			if errors.Is(ve.Inner, target) {
+				return ve.Inner
+			}
+		}
+	}
+	switch {
+	case ve.Errors&jwt.ValidationErrorMalformed != 0:
+		return fmt.Errorf("%w:%s", ErrTokenMalformed, ve.Error())
+	case ve.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) != 0:
+		return fmt.Errorf("%w:%s", ErrSignatureInvalid, ve.Error())
+	}
+	return err
+}
+
+// StatusCode token校验错误对应的http状态码
+func StatusCode(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrInvalidAudience):
+		return http.StatusForbidden
+	case errors.Is(err, ErrTokenMalformed):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotInitialized):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusUnauthorized
+	}
+}
@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hoorayui/core-framework/components/config"
+	"github.com/hoorayui/core-framework/components/mysql"
+	"github.com/hoorayui/core-framework/components/redis"
+	"github.com/hoorayui/core-framework/types"
+	"github.com/sirupsen/logrus"
+)
+
+// weakSecrets 禁止使用的密钥，包含旧版本写在代码中的密钥
+var weakSecrets = map[string]bool{
+	"M@O8VWUb81YvmtWLHGB2I_V7di5-@0p(MF*GrE!sIws23F": true,
+	"secret":   true,
+	"changeme": true,
+}
+
+type Instance struct {
+	config types.JWTConfig
+	secret []byte
+	keys   *KeySet // 非对称签名的密钥，HS256时为nil
+	// 允许的签名算法
+	allowed map[string]bool
+	refresh RefreshStore
+	// 会话管理，未启用时为nil
+	sessions *SessionStore
+}
+
+var instance *Instance
+
+// New 根据配置创建实例，不替换全局实例
+func New(config types.JWTConfig) (*Instance, error) {
+	i := &Instance{config: config}
+	if err := i.Validate(); err != nil {
+		return nil, err
+	}
+	if err := i.initKeys(); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// GetName 组件名称
+func (i *Instance) GetName() string {
+	return "jwt"
+}
+
+// Init 初始化实例
+func (i *Instance) Init(config interface{}) error {
+	bytes, _ := json.Marshal(config)
+	json.Unmarshal(bytes, &i.config)
+	if err := i.Validate(); err != nil {
+		return err
+	}
+	if err := i.initKeys(); err != nil {
+		return err
+	}
+	if err := i.initRefresh(); err != nil {
+		return err
+	}
+	if err := i.initSessions(); err != nil {
+		return err
+	}
+	instance = i
+	return nil
+}
+
+// initKeys 加载签名密钥
+func (i *Instance) initKeys() error {
+	i.allowed = map[string]bool{}
+	for _, alg := range i.config.AllowedAlgorithms {
+		i.allowed[alg] = true
+	}
+	if len(i.allowed) == 0 {
+		i.allowed[i.config.Algorithm] = true
+	}
+	if i.config.Algorithm == AlgHS256 {
+		i.secret = []byte(i.config.Secret)
+		return nil
+	}
+	leeway := time.Duration(i.config.Leeway) * time.Second
+	i.keys = &KeySet{Leeway: leeway}
+	// 倒序加入，使第一个密钥成为最新的密钥，之前的签名密钥在新密钥生效后继续校验到已签发的token过期
+	for idx := len(i.config.Keys) - 1; idx >= 0; idx-- {
+		k, err := LoadKey(i.config.Keys[idx])
+		if err != nil {
+			return err
+		}
+		if k.Private != nil && k.Method.Alg() != i.config.Algorithm {
+			return fmt.Errorf("jwt签名密钥[%s]的算法[%s]与配置的算法[%s]不一致", k.ID, k.Method.Alg(), i.config.Algorithm)
+		}
+		if len(i.config.AllowedAlgorithms) == 0 {
+			i.allowed[k.Method.Alg()] = true
+		}
+		i.keys.Add(k, i.Expire()+leeway)
+	}
+	if i.keys.Signing() == nil {
+		return fmt.Errorf("jwt未配置已生效的[%s]算法签名私钥", i.config.Algorithm)
+	}
+	return nil
+}
+
+// initRefresh 创建刷新token存储
+func (i *Instance) initRefresh() error {
+	switch i.config.RefreshStore {
+	case RefreshStoreRedis:
+		if redis.GetInstance() == nil || redis.GetInstance().Client() == nil {
+			return errors.New("刷新token存储需要先加载redis组件")
+		}
+		i.refresh = NewRedisRefreshStore(redis.GetInstance().Client(), i.config.KeyPrefix)
+	case RefreshStoreMysql:
+		if mysql.GetInstance() == nil || mysql.GetInstance().Client() == nil {
+			return errors.New("刷新token存储需要先加载mysql组件")
+		}
+		store, err := NewGormRefreshStore(mysql.GetInstance().Client())
+		if err != nil {
+			return err
+		}
+		i.refresh = store
+	}
+	return nil
+}
+
+// initSessions 创建会话存储
+func (i *Instance) initSessions() error {
+	if !i.config.Sessions {
+		return nil
+	}
+	if redis.GetInstance() == nil || redis.GetInstance().Client() == nil {
+		return errors.New("会话管理需要先加载redis组件")
+	}
+	i.sessions = NewSessionStore(redis.GetInstance().Client(), i.config.KeyPrefix)
+	return nil
+}
+
+// Validate 验证配置，HS256密钥为空或为默认密钥时返回错误
+func (i *Instance) Validate() error {
+	switch i.config.Algorithm {
+	case "":
+		i.config.Algorithm = AlgHS256
+	case AlgHS256, AlgRS256, AlgES256, AlgEdDSA:
+	default:
+		return fmt.Errorf("不支持的jwt签名算法[%s]", i.config.Algorithm)
+	}
+	signAllowed := len(i.config.AllowedAlgorithms) == 0
+	for _, alg := range i.config.AllowedAlgorithms {
+		switch alg {
+		case AlgHS256, AlgRS256, AlgES256, AlgEdDSA:
+		default:
+			return fmt.Errorf("不支持的jwt签名算法[%s]", alg)
+		}
+		signAllowed = signAllowed || alg == i.config.Algorithm
+	}
+	if !signAllowed {
+		return fmt.Errorf("允许的签名算法中缺少签名算法[%s]", i.config.Algorithm)
+	}
+	if i.config.Algorithm == AlgHS256 {
+		if i.config.Secret == "" {
+			return errors.New("jwt密钥未配置")
+		}
+		if weakSecrets[i.config.Secret] {
+			return errors.New("jwt密钥不能使用默认值，请重新生成")
+		}
+		if len(i.config.Secret) < 32 {
+			logrus.Warn("jwt密钥长度小于32字节，为了安全，请使用更长的密钥")
+		}
+	} else if len(i.config.Keys) == 0 {
+		// 多实例部署时各实例需要使用相同的密钥，不自动生成
+		return fmt.Errorf("jwt使用[%s]算法时需要配置签名密钥", i.config.Algorithm)
+	}
+	if i.config.Expire <= 0 {
+		// 兼容旧配置token_expire_duration，单位为分钟
+		if options := config.GetOptions(); options != nil && options.TokenExpireDuration > 0 {
+			i.config.Expire = options.TokenExpireDuration * 60
+		} else {
+			i.config.Expire = int(TokenExpireDuration / time.Second)
+		}
+	}
+	if i.config.Leeway < 0 {
+		return errors.New("jwt时间误差不能小于0")
+	}
+	switch i.config.RefreshStore {
+	case "", RefreshStoreRedis, RefreshStoreMysql:
+	default:
+		return fmt.Errorf("不支持的刷新token存储[%s]", i.config.RefreshStore)
+	}
+	if i.config.RefreshExpire <= 0 {
+		i.config.RefreshExpire = 7 * 24 * 3600
+	}
+	if i.config.KeyPrefix == "" {
+		i.config.KeyPrefix = "jwt:"
+	}
+	return nil
+}
+
+// Expire token有效期
+func (i *Instance) Expire() time.Duration {
+	return time.Duration(i.config.Expire) * time.Second
+}
+
+// GetInstance 获取实例
+func GetInstance() *Instance {
+	return instance
+}
+
+// Keys 非对称签名的密钥集合，HS256时为nil
+func (i *Instance) Keys() *KeySet {
+	return i.keys
+}
+
+// Close 关闭
+func (i *Instance) Close() {
+}
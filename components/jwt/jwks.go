@@ -0,0 +1,152 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// JWKSPath JWKS的默认路由
+const JWKSPath = "/.well-known/jwks.json"
+
+// JWK 公钥，格式见RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS 公钥集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func encodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// padded 按曲线长度补齐坐标
+func padded(n *big.Int, size int) string {
+	b := make([]byte, size)
+	return encodeBase64(n.FillBytes(b))
+}
+
+// JWK 转换为JWK格式
+func (k *Key) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64(pub.N.Bytes())
+		jwk.E = encodeBase64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = padded(pub.X, size)
+		jwk.Y = padded(pub.Y, size)
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64(pub)
+	}
+	return jwk
+}
+
+// Key 转换为只用于校验的密钥，用于校验其他服务签发的token，支持RS256、ES256和EdDSA
+func (j JWK) Key() (*Key, error) {
+	alg := j.Alg
+	var pub interface{}
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("公钥[%s]的n不正确:%w", j.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("公钥[%s]的e不正确:%w", j.Kid, err)
+		}
+		pub = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if alg == "" {
+			alg = AlgRS256
+		}
+	case "EC":
+		if j.Crv != elliptic.P256().Params().Name {
+			return nil, fmt.Errorf("公钥[%s]的曲线[%s]不支持", j.Kid, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("公钥[%s]的x不正确:%w", j.Kid, err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("公钥[%s]的y不正确:%w", j.Kid, err)
+		}
+		pub = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if alg == "" {
+			alg = AlgES256
+		}
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || j.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("公钥[%s]不是Ed25519公钥", j.Kid)
+		}
+		pub = ed25519.PublicKey(x)
+		if alg == "" {
+			alg = AlgEdDSA
+		}
+	default:
+		return nil, fmt.Errorf("公钥[%s]的类型[%s]不支持", j.Kid, j.Kty)
+	}
+	var method jwt.SigningMethod
+	switch alg {
+	case AlgRS256:
+		method = jwt.SigningMethodRS256
+	case AlgES256:
+		method = jwt.SigningMethodES256
+	case AlgEdDSA:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("公钥[%s]的算法[%s]不支持", j.Kid, alg)
+	}
+	return &Key{ID: j.Kid, Method: method, Public: pub}, nil
+}
+
+// JWKS 所有可用于校验的公钥，HS256密钥不公开
+func (i *Instance) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if i.keys == nil {
+		return jwks
+	}
+	for _, k := range i.keys.Keys() {
+		jwks.Keys = append(jwks.Keys, k.JWK())
+	}
+	return jwks
+}
+
+// JWKSHandler 公开校验token的公钥，通常注册到JWKSPath
+func JWKSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if instance == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": ErrNotInitialized.Error()})
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, instance.JWKS())
+	}
+}
@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/hoorayui/core-framework/util"
@@ -9,75 +10,172 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
-const TokenExpireDuration = time.Minute * 15 // 两小时过期
+// TokenExpireDuration 未配置过期时间时的默认值
+const TokenExpireDuration = time.Minute * 15 // 15分钟过期
 
-var privateKey = []byte("M@O8VWUb81YvmtWLHGB2I_V7di5-@0p(MF*GrE!sIws23F")
+// ErrNotInitialized jwt组件未加载
+var ErrNotInitialized = errors.New("jwt组件未初始化")
 
-type myClaims struct {
-	UserID   int64  `json:"user_id"`
-	Username string `json:"username"`
+// Claims token中的声明
+type Claims struct {
+	UserID    int64  `json:"user_id"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid,omitempty"` // 会话ID，同一次登录刷新出的token相同
 	jwt.StandardClaims
 }
 
-func (*myClaims) Valid() error {
-	return nil
+// Registered 标准声明
+func (c *Claims) Registered() *jwt.StandardClaims {
+	return &c.StandardClaims
 }
 
-// GetToken 生成JWT
-func GenToken(userID int64, username string) (string, error) {
-	// 创建一个我们自己的声明数据
-	c := &myClaims{
-		userID,
-		username,
-		jwt.StandardClaims{
-			ExpiresAt: util.Now().Add(TokenExpireDuration).Unix(), // 过期时间
-			Issuer:    "fivegold",                                 // 签发人
-		},
-	}
-
-	// 使用指定的签名方法创建签名对象
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
-	// 使用指定的secret签名并获得完整的编码后的字符串token
-	return token.SignedString(privateKey)
+// session 用于撤销校验的用户ID和会话ID
+func (c *Claims) session() (int64, string) {
+	return c.UserID, c.SessionID
 }
 
-// ParseToken 解析JWT
-func ParseToken(tokenString string) (*myClaims, error) {
-	mc := new(myClaims)
-	token, err := jwt.ParseWithClaims(tokenString, mc, func(token *jwt.Token) (interface{}, error) {
-		return privateKey, nil
-	})
+// TokenOption 签发选项
+type TokenOption func(*Session)
+
+// WithClient 记录会话的设备和IP，启用会话管理时用于会话列表
+func WithClient(device, ip string) TokenOption {
+	return func(s *Session) {
+		s.Device = device
+		s.IP = ip
+	}
+}
+
+// GenToken 生成JWT，启用会话管理时每个token为一个会话
+func (i *Instance) GenToken(userID int64, username string, opts ...TokenOption) (string, error) {
+	jti := util.NewUUIDString("")
+	token, err := i.genToken(userID, username, jti, jti)
 	if err != nil {
+		return "", err
+	}
+	return token, i.saveSession(userID, username, jti, i.Expire(), opts)
+}
+
+func (i *Instance) genToken(userID int64, username, jti, sid string) (string, error) {
+	return i.Issue(&Claims{
+		UserID:         userID,
+		Username:       username,
+		SessionID:      sid,
+		StandardClaims: jwt.StandardClaims{Id: jti},
+	})
+}
+
+// sign 使用当前的签名密钥签名，非对称签名在header中写入kid
+func (i *Instance) sign(claims jwt.Claims) (string, error) {
+	if i.keys == nil {
+		// 使用指定的签名方法创建签名对象
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		// 使用指定的secret签名并获得完整的编码后的字符串token
+		return token.SignedString(i.secret)
+	}
+	k := i.keys.Signing()
+	if k == nil {
+		return "", errors.New("没有可用的签名密钥")
+	}
+	token := jwt.NewWithClaims(k.Method, claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.Private)
+}
+
+// keyFunc 校验签名算法在允许列表中，按kid选择校验密钥，token的算法必须与密钥一致
+func (i *Instance) keyFunc(token *jwt.Token) (interface{}, error) {
+	alg := token.Method.Alg()
+	if !i.allowed[alg] {
+		return nil, fmt.Errorf("%w[%s]", ErrAlgorithmNotAllowed, alg)
+	}
+	if i.keys == nil {
+		if alg != AlgHS256 {
+			return nil, fmt.Errorf("%w[%s]", ErrAlgorithmNotAllowed, alg)
+		}
+		return i.secret, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	k := i.keys.Lookup(kid)
+	if k == nil {
+		return nil, fmt.Errorf("%w[%s]", ErrUnknownKey, kid)
+	}
+	if alg != k.Method.Alg() {
+		return nil, fmt.Errorf("%w:密钥[%s]只支持[%s]，实际为[%s]", ErrAlgorithmNotAllowed, kid, k.Method.Alg(), alg)
+	}
+	return k.Public, nil
+}
+
+// ParseToken 解析JWT，校验签名、过期时间、签发人和接收方
+func (i *Instance) ParseToken(tokenString string) (*Claims, error) {
+	return ParseWith[*Claims](i, tokenString)
+}
+
+// parse 解析JWT，签名正确但声明校验失败时同时返回声明和错误
+func (i *Instance) parse(tokenString string) (*Claims, error) {
+	mc := new(Claims)
+	signed, err := i.parseInto(tokenString, mc)
+	if !signed {
 		return nil, err
 	}
+	return mc, err
+}
 
-	if token.Valid { // 校验token
-		return mc, nil
+// verify 校验过期时间、生效时间、签发时间、签发人和接收方，时间允许Leeway的误差
+func (i *Instance) verify(c *jwt.StandardClaims) error {
+	now := util.Now().Unix()
+	leeway := int64(i.config.Leeway)
+	if c.ExpiresAt == 0 {
+		return fmt.Errorf("%w[exp]", ErrClaimMissing)
+	}
+	if now > c.ExpiresAt+leeway {
+		return ErrTokenExpired
+	}
+	if c.NotBefore > 0 && now+leeway < c.NotBefore {
+		return ErrTokenNotValidYet
+	}
+	if c.IssuedAt > 0 && now+leeway < c.IssuedAt {
+		return ErrTokenUsedBeforeIssued
 	}
-	return nil, errors.New("invalid token")
+	if i.config.Issuer != "" && c.Issuer != i.config.Issuer {
+		return ErrInvalidIssuer
+	}
+	if i.config.Audience != "" && !c.VerifyAudience(i.config.Audience, true) {
+		return ErrInvalidAudience
+	}
+	return nil
 }
 
-func CheckToken(tokenString string) (string, bool) {
-	if tokenString == "" || len(tokenString) <= 0 {
+// CheckToken 校验JWT，返回用户名和是否有效
+func (i *Instance) CheckToken(tokenString string) (string, bool) {
+	if tokenString == "" {
 		return "", false
 	}
-
-	mc := new(myClaims)
-	token, err := jwt.ParseWithClaims(tokenString, mc, func(token *jwt.Token) (interface{}, error) {
-		return privateKey, nil
-	})
-	if err != nil {
+	mc, err := i.parse(tokenString)
+	if mc == nil {
 		return "", false
 	}
+	return mc.Username, err == nil
+}
 
-	if !token.Valid {
-		return "", false
+// GenToken 生成JWT
+func GenToken(userID int64, username string, opts ...TokenOption) (string, error) {
+	if instance == nil {
+		return "", ErrNotInitialized
 	}
+	return instance.GenToken(userID, username, opts...)
+}
 
-	now := util.Now()
-	if mc.ExpiresAt <= now.Unix() {
-		return mc.Username, false
-	} // 过期
+// ParseToken 解析JWT
+func ParseToken(tokenString string) (*Claims, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+	return instance.ParseToken(tokenString)
+}
 
-	return mc.Username, true
+// CheckToken 校验JWT
+func CheckToken(tokenString string) (string, bool) {
+	if instance == nil {
+		return "", false
+	}
+	return instance.CheckToken(tokenString)
 }
@@ -1,11 +1,30 @@
 package jwt
 
-import "testing"
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/hoorayui/core-framework/types"
+)
+
+func TestMain(m *testing.M) {
+	i := &Instance{}
+	if err := i.Init(types.JWTConfig{
+		Secret: "test-secret-0123456789abcdefghijklmnop",
+		Issuer: "core-framework",
+	}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
 
 func TestGenToken(t *testing.T) {
 	got, err := GenToken(1, "张三")
 	if err != nil {
-		t.Logf("获取JWT Token失败")
+		t.Fatalf("获取JWT Token失败:%s", err.Error())
 	}
 	t.Logf(got)
 }
@@ -14,7 +33,7 @@ func TestParseToken(t *testing.T) {
 	str, err := GenToken(1, "张三")
 	claims, err := ParseToken(str)
 	if err != nil {
-		t.Logf("解析JWT Token失败")
+		t.Fatalf("解析JWT Token失败:%s", err.Error())
 	}
 	t.Logf("\n用户ID:[%v]\n用户名:[%v]\nStandardClaims:[%+v]\n", claims.UserID, claims.Username, claims.StandardClaims)
 }
@@ -28,3 +47,116 @@ func TestCheckToken(t *testing.T) {
 	userName, valid := CheckToken(str)
 	t.Logf("用户名:[%v]\n是否有效:[%v]\n", userName, valid)
 }
+
+func TestValidateSecret(t *testing.T) {
+	for _, secret := range []string{"", "M@O8VWUb81YvmtWLHGB2I_V7di5-@0p(MF*GrE!sIws23F"} {
+		if _, err := New(types.JWTConfig{Secret: secret}); err == nil {
+			t.Fatalf("密钥[%s]应校验失败", secret)
+		}
+	}
+}
+
+func TestDefaultExpire(t *testing.T) {
+	i, err := New(types.JWTConfig{Secret: "test-secret-0123456789abcdefghijklmnop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Expire() != 15*time.Minute {
+		t.Errorf("默认过期时间:%s", i.Expire())
+	}
+}
+
+func TestIssuerMismatch(t *testing.T) {
+	other, err := New(types.JWTConfig{
+		Secret: "test-secret-0123456789abcdefghijklmnop",
+		Issuer: "other",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	str, _ := other.GenToken(1, "张三")
+	if _, err := ParseToken(str); err == nil {
+		t.Fatal("签发人不同时应校验失败")
+	}
+}
+
+func TestClaimErrors(t *testing.T) {
+	i, err := New(types.JWTConfig{
+		Secret:   "test-secret-0123456789abcdefghijklmnop",
+		Audience: "web",
+		Leeway:   60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	cases := []struct {
+		name   string
+		claims jwt.StandardClaims
+		want   error
+	}{
+		{"误差内过期", jwt.StandardClaims{Audience: "web", ExpiresAt: now - 30}, nil},
+		{"已过期", jwt.StandardClaims{Audience: "web", ExpiresAt: now - 120}, ErrTokenExpired},
+		{"未生效", jwt.StandardClaims{Audience: "web", ExpiresAt: now + 600, NotBefore: now + 300}, ErrTokenNotValidYet},
+		{"签发时间", jwt.StandardClaims{Audience: "web", ExpiresAt: now + 600, IssuedAt: now + 300}, ErrTokenUsedBeforeIssued},
+		{"接收方", jwt.StandardClaims{Audience: "app", ExpiresAt: now + 600}, ErrInvalidAudience},
+		{"缺少过期时间", jwt.StandardClaims{Audience: "web"}, ErrClaimMissing},
+	}
+	for _, c := range cases {
+		str, _ := i.sign(&Claims{UserID: 1, StandardClaims: c.claims})
+		if _, err := i.ParseToken(str); !errors.Is(err, c.want) && !(c.want == nil && err == nil) {
+			t.Fatalf("[%s]期望%v，实际%v", c.name, c.want, err)
+		}
+	}
+
+	str, _ := i.GenToken(1, "张三")
+	if _, err := i.ParseToken(str + "x"); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("签名错误，实际%v", err)
+	}
+	if _, err := i.ParseToken("abc"); !errors.Is(err, ErrTokenMalformed) {
+		t.Fatalf("格式错误，实际%v", err)
+	}
+}
+
+func TestAlgorithmNotAllowed(t *testing.T) {
+	i, err := New(types.JWTConfig{Algorithm: AlgRS256, Keys: []types.JWTKey{writeKey(t, AlgRS256, false)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := &Claims{StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Unix() + 600}}
+	// 使用公钥作为HMAC密钥伪造token
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = i.Keys().Signing().ID
+	str, _ := token.SignedString([]byte("public-key"))
+	if _, err := i.ParseToken(str); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Fatalf("期望算法不允许，实际%v", err)
+	}
+	str, _ = jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := i.ParseToken(str); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Fatalf("期望算法不允许，实际%v", err)
+	}
+}
+
+type tenantClaims struct {
+	Claims
+	TenantID int64    `json:"tenant_id"`
+	Roles    []string `json:"roles"`
+}
+
+func TestCustomClaims(t *testing.T) {
+	str, err := Issue(&tenantClaims{Claims: Claims{UserID: 1}, TenantID: 7, Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := Parse[*tenantClaims](str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != 1 || claims.TenantID != 7 || len(claims.Roles) != 1 || claims.Issuer != "core-framework" {
+		t.Fatalf("自定义声明不正确:%+v", claims)
+	}
+	// 默认声明也可以按自定义声明解析
+	if _, err := Parse[*RegisteredClaims](str); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,258 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/hoorayui/core-framework/types"
+	"github.com/hoorayui/core-framework/util"
+)
+
+// 签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Key 签名密钥，只有公钥的密钥只用于校验
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	Private    crypto.PrivateKey
+	Public     crypto.PublicKey
+	CreatedAt  time.Time
+	ActivateAt time.Time // 开始用于签名的时间，零值表示立即生效
+	ExpireAt   time.Time // 轮换后停止校验的时间，零值表示一直有效
+}
+
+// NewKey 生成指定算法的密钥，kid为公钥的sha256摘要
+func NewKey(alg string) (*Key, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("不支持生成[%s]算法的密钥", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newKey("", private, private.Public())
+}
+
+func newKey(id string, private crypto.PrivateKey, public crypto.PublicKey) (*Key, error) {
+	k := &Key{ID: id, Private: private, Public: public, CreatedAt: util.Now()}
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		k.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("ES256只支持P-256曲线")
+		}
+		k.Method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		k.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("不支持的公钥类型[%T]", public)
+	}
+	if k.ID == "" {
+		der, err := x509.MarshalPKIXPublicKey(public)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(der)
+		k.ID = base64.RawURLEncoding.EncodeToString(sum[:16])
+	}
+	return k, nil
+}
+
+// LoadKey 从PEM文件加载密钥，配置了私钥时用于签名和校验，只配置公钥时只用于校验
+func LoadKey(cfg types.JWTKey) (*Key, error) {
+	k, err := loadKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if k.ActivateAt, err = parseKeyTime(cfg.ActivateAt); err != nil {
+		return nil, fmt.Errorf("密钥[%s]的生效时间不正确:%s", k.ID, err.Error())
+	}
+	if k.ExpireAt, err = parseKeyTime(cfg.ExpireAt); err != nil {
+		return nil, fmt.Errorf("密钥[%s]的过期时间不正确:%s", k.ID, err.Error())
+	}
+	return k, nil
+}
+
+func loadKey(cfg types.JWTKey) (*Key, error) {
+	if cfg.PrivateKeyFile != "" {
+		b, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		private, err := parsePrivateKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥[%s]失败:%s", cfg.PrivateKeyFile, err.Error())
+		}
+		return newKey(cfg.ID, private, private.Public())
+	}
+	if cfg.PublicKeyFile == "" {
+		return nil, errors.New("密钥未配置私钥或公钥文件")
+	}
+	b, err := os.ReadFile(cfg.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	public, err := parsePublicKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("解析公钥[%s]失败:%s", cfg.PublicKeyFile, err.Error())
+	}
+	return newKey(cfg.ID, nil, public)
+}
+
+func parseKeyTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func parsePrivateKey(b []byte) (crypto.Signer, error) {
+	if k, err := jwt.ParseRSAPrivateKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	if k, err := jwt.ParseECPrivateKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	k, err := jwt.ParseEdPrivateKeyFromPEM(b)
+	if err != nil {
+		return nil, errors.New("只支持RSA、ECDSA、Ed25519私钥")
+	}
+	return k.(crypto.Signer), nil
+}
+
+func parsePublicKey(b []byte) (crypto.PublicKey, error) {
+	if k, err := jwt.ParseRSAPublicKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	if k, err := jwt.ParseECPublicKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	k, err := jwt.ParseEdPublicKeyFromPEM(b)
+	if err != nil {
+		return nil, errors.New("只支持RSA、ECDSA、Ed25519公钥")
+	}
+	return k, nil
+}
+
+// KeySet 密钥集合，最新的已生效的带私钥的密钥用于签名，未过期的密钥都可用于校验
+type KeySet struct {
+	// Leeway 密钥过期后继续校验的时间，与校验token时间允许的误差一致
+	Leeway time.Duration
+
+	l    sync.RWMutex
+	keys []*Key
+}
+
+// Add 加入密钥，带私钥的密钥在生效后成为新的签名密钥。
+// overlap大于0且原签名密钥未设置过期时间时，原签名密钥在新密钥生效overlap后停止校验
+func (s *KeySet) Add(k *Key, overlap time.Duration) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if k.Private != nil && overlap > 0 {
+		if prev := s.latest(); prev != nil && prev.ExpireAt.IsZero() {
+			from := k.ActivateAt
+			if from.IsZero() {
+				from = util.Now()
+			}
+			prev.ExpireAt = from.Add(overlap)
+		}
+	}
+	s.keys = append([]*Key{k}, s.keys...)
+	s.prune()
+}
+
+// expired 密钥已过期，超过Leeway后不再用于校验
+func (s *KeySet) expired(k *Key, now time.Time) bool {
+	return !k.ExpireAt.IsZero() && !now.Before(k.ExpireAt.Add(s.Leeway))
+}
+
+// prune 删除已过期的密钥，调用方需持有写锁
+func (s *KeySet) prune() {
+	now := util.Now()
+	keys := s.keys[:0]
+	for _, k := range s.keys {
+		if !s.expired(k, now) {
+			keys = append(keys, k)
+		}
+	}
+	s.keys = keys
+}
+
+// latest 最后加入的带私钥的密钥，可能尚未生效
+func (s *KeySet) latest() *Key {
+	for _, k := range s.keys {
+		if k.Private != nil {
+			return k
+		}
+	}
+	return nil
+}
+
+func (s *KeySet) signing() *Key {
+	now := util.Now()
+	for _, k := range s.keys {
+		if k.Private == nil || now.Before(k.ActivateAt) {
+			continue
+		}
+		if k.ExpireAt.IsZero() || now.Before(k.ExpireAt) {
+			return k
+		}
+	}
+	return nil
+}
+
+// Signing 当前的签名密钥
+func (s *KeySet) Signing() *Key {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	return s.signing()
+}
+
+// Lookup 按kid查找未过期的密钥，尚未生效的密钥也可用于校验
+func (s *KeySet) Lookup(kid string) *Key {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	now := util.Now()
+	for _, k := range s.keys {
+		if k.ID == kid && !s.expired(k, now) {
+			return k
+		}
+	}
+	return nil
+}
+
+// Keys 所有未过期的密钥
+func (s *KeySet) Keys() []*Key {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.prune()
+	return append([]*Key{}, s.keys...)
+}
@@ -0,0 +1,202 @@
+package jwt
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoorayui/core-framework/types"
+)
+
+// writeKey 生成密钥并写入PEM文件，public为true时只写入公钥
+func writeKey(t *testing.T, alg string, public bool) types.JWTKey {
+	t.Helper()
+	k, err := NewKey(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(k.Public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	path := filepath.Join(t.TempDir(), k.ID+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if public {
+		return types.JWTKey{PublicKeyFile: path}
+	}
+	return types.JWTKey{PrivateKeyFile: path}
+}
+
+func TestAsymmetricAlgorithms(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgES256, AlgEdDSA} {
+		i, err := New(types.JWTConfig{Algorithm: alg, Keys: []types.JWTKey{writeKey(t, alg, false)}})
+		if err != nil {
+			t.Fatalf("[%s]创建失败:%s", alg, err.Error())
+		}
+		str, err := i.GenToken(1, "张三")
+		if err != nil {
+			t.Fatalf("[%s]签名失败:%s", alg, err.Error())
+		}
+		claims, err := i.ParseToken(str)
+		if err != nil || claims.UserID != 1 {
+			t.Fatalf("[%s]校验失败:%v", alg, err)
+		}
+		// 其他实例的密钥不能校验
+		other, _ := New(types.JWTConfig{Algorithm: alg, Keys: []types.JWTKey{writeKey(t, alg, false)}})
+		if _, err := other.ParseToken(str); err == nil {
+			t.Fatalf("[%s]其他密钥不应校验通过", alg)
+		}
+	}
+}
+
+func TestValidateKeys(t *testing.T) {
+	if _, err := New(types.JWTConfig{Algorithm: AlgRS256}); err == nil {
+		t.Error("未配置密钥时应返回错误，不能生成临时密钥")
+	}
+	if _, err := New(types.JWTConfig{Algorithm: AlgRS256, Keys: []types.JWTKey{writeKey(t, AlgRS256, true)}}); err == nil {
+		t.Error("只配置公钥时应返回错误")
+	}
+	if _, err := New(types.JWTConfig{Algorithm: AlgES256, Keys: []types.JWTKey{writeKey(t, AlgRS256, false)}}); err == nil {
+		t.Error("签名密钥与算法不一致时应返回错误")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := writeKey(t, AlgES256, false)
+	i, err := New(types.JWTConfig{Algorithm: AlgES256, Keys: []types.JWTKey{oldKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, _ := i.GenToken(1, "张三")
+	oldKid := i.Keys().Signing().ID
+
+	// 新密钥加到最前面，旧密钥继续用于校验
+	rotated, err := New(types.JWTConfig{Algorithm: AlgES256, Keys: []types.JWTKey{writeKey(t, AlgES256, false), oldKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Keys().Signing().ID == oldKid {
+		t.Fatal("轮换后签名密钥未变化")
+	}
+	if _, err := rotated.ParseToken(old); err != nil {
+		t.Fatalf("旧密钥签发的token应校验通过:%s", err.Error())
+	}
+	if n := len(rotated.JWKS().Keys); n != 2 {
+		t.Fatalf("JWKS应包含2个公钥，实际%d个", n)
+	}
+	str, _ := rotated.GenToken(1, "张三")
+	if _, err := i.ParseToken(str); err == nil {
+		t.Fatal("未配置新密钥时不应校验通过")
+	}
+}
+
+func TestKeyRotationConfig(t *testing.T) {
+	oldKey := writeKey(t, AlgES256, false)
+	i, err := New(types.JWTConfig{Algorithm: AlgES256, Keys: []types.JWTKey{oldKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 轮换前签发的token
+	old, _ := i.GenToken(1, "张三")
+	oldKid := i.Keys().Signing().ID
+	newKey := writeKey(t, AlgES256, false)
+	now := time.Now()
+	rotate := func(activateAt, expireAt time.Time) *Instance {
+		t.Helper()
+		next, cur := newKey, oldKey
+		next.ActivateAt = activateAt.Format(time.RFC3339)
+		if !expireAt.IsZero() {
+			cur.ExpireAt = expireAt.Format(time.RFC3339)
+		}
+		rotated, err := New(types.JWTConfig{Algorithm: AlgES256, Leeway: 60, Keys: []types.JWTKey{next, cur}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rotated
+	}
+
+	if rotate(now.Add(time.Hour), time.Time{}).Keys().Signing().ID != oldKid {
+		t.Error("新密钥生效前应继续使用旧密钥签名")
+	}
+	// 新密钥生效后旧密钥继续校验到token有效期加时间误差
+	rotated := rotate(now.Add(-time.Minute), time.Time{})
+	if rotated.Keys().Signing().ID == oldKid {
+		t.Error("新密钥生效后应使用新密钥签名")
+	}
+	if _, err := rotated.ParseToken(old); err != nil {
+		t.Errorf("重叠时间内旧密钥签发的token应校验通过:%v", err)
+	}
+	if _, err := rotate(now.Add(-18*time.Minute), time.Time{}).ParseToken(old); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("重叠时间之后旧密钥签发的token应校验失败:%v", err)
+	}
+	// 指定旧密钥的过期时间
+	if _, err := rotate(now.Add(-time.Minute), now.Add(-30*time.Second)).ParseToken(old); err != nil {
+		t.Errorf("过期时间误差内旧密钥签发的token应校验通过:%v", err)
+	}
+	if _, err := rotate(now.Add(-time.Minute), now.Add(-2*time.Minute)).ParseToken(old); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("旧密钥过期后签发的token应校验失败:%v", err)
+	}
+
+	if _, err := New(types.JWTConfig{Algorithm: AlgES256, Keys: []types.JWTKey{{PrivateKeyFile: newKey.PrivateKeyFile, ActivateAt: "tomorrow"}}}); err == nil {
+		t.Error("生效时间格式不正确时应返回错误")
+	}
+}
+
+func TestKeySetOverlap(t *testing.T) {
+	oldKey, _ := NewKey(AlgEdDSA)
+	newKey, _ := NewKey(AlgEdDSA)
+	s := &KeySet{}
+	s.Add(oldKey, 0)
+	s.Add(newKey, time.Hour)
+	if s.Signing() != newKey || s.Lookup(oldKey.ID) != oldKey {
+		t.Fatal("重叠时间内旧密钥应继续用于校验")
+	}
+	oldKey.ExpireAt = time.Now().Add(-time.Second)
+	if s.Lookup(oldKey.ID) != nil || len(s.Keys()) != 1 {
+		t.Fatal("过期的密钥不应用于校验")
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	prev := instance
+	defer func() { instance = prev }()
+	i := &Instance{}
+	if err := i.Init(types.JWTConfig{Algorithm: AlgRS256, Keys: []types.JWTKey{writeKey(t, AlgRS256, false)}}); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET(JWKSPath, JWKSHandler())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, JWKSPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码%d", w.Code)
+	}
+	jwks := JWKS{}
+	if err := json.Unmarshal(w.Body.Bytes(), &jwks); err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kty != "RSA" || jwks.Keys[0].Kid != i.Keys().Signing().ID {
+		t.Fatalf("JWKS不正确:%s", w.Body.String())
+	}
+}
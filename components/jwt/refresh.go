@@ -0,0 +1,332 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+	"github.com/hoorayui/core-framework/util"
+	"gorm.io/gorm"
+)
+
+// 刷新token存储类型
+const (
+	RefreshStoreRedis = "redis"
+	RefreshStoreMysql = "mysql"
+)
+
+// 刷新token错误
+var (
+	ErrRefreshDisabled     = errors.New("未配置刷新token存储")
+	ErrRefreshTokenInvalid = errors.New("刷新token无效")
+	ErrRefreshTokenExpired = errors.New("刷新token已过期")
+	ErrRefreshTokenReused  = errors.New("刷新token重复使用，已撤销该登录的所有刷新token")
+)
+
+// TokenPair 访问token和刷新token
+type TokenPair struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`         // 访问token有效期，秒
+	RefreshExpiresIn int64  `json:"refresh_expires_in"` // 刷新token有效期，秒
+}
+
+// RefreshToken 服务端保存的刷新token，ID为token的sha256摘要，
+// 同一次登录轮换出的刷新token属于同一个Family
+type RefreshToken struct {
+	ID        string     `json:"id" gorm:"primaryKey;size:64"`
+	Family    string     `json:"family" gorm:"size:64;index"`
+	UserID    int64      `json:"user_id" gorm:"index"`
+	Username  string     `json:"username" gorm:"size:255"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 表名
+func (RefreshToken) TableName() string {
+	return "jwt_refresh_token"
+}
+
+// RefreshStore 刷新token存储
+type RefreshStore interface {
+	Save(t *RefreshToken) error
+	Get(id string) (*RefreshToken, error) // 不存在时返回ErrRefreshTokenInvalid
+	MarkUsed(id string) (bool, error)     // 标记为已使用，已被使用过时返回false，不存在时返回ErrRefreshTokenInvalid
+	RevokeFamily(family string) error     // 删除同一次登录的所有刷新token
+}
+
+// SetRefreshStore 设置刷新token存储
+func (i *Instance) SetRefreshStore(store RefreshStore) {
+	i.refresh = store
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// RefreshExpire 刷新token有效期
+func (i *Instance) RefreshExpire() time.Duration {
+	return time.Duration(i.config.RefreshExpire) * time.Second
+}
+
+// IssuePair 登录时签发访问token和刷新token，启用会话管理时一次登录为一个会话
+func (i *Instance) IssuePair(userID int64, username string, opts ...TokenOption) (*TokenPair, error) {
+	family := util.NewUUIDString("")
+	pair, err := i.issuePair(userID, username, family)
+	if err != nil {
+		return nil, err
+	}
+	return pair, i.saveSession(userID, username, family, i.RefreshExpire(), opts)
+}
+
+func (i *Instance) issuePair(userID int64, username, family string) (*TokenPair, error) {
+	if i.refresh == nil {
+		return nil, ErrRefreshDisabled
+	}
+	access, err := i.genToken(userID, username, util.NewUUIDString(""), family)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	now := util.Now()
+	err = i.refresh.Save(&RefreshToken{
+		ID:        hashRefreshToken(refresh),
+		Family:    family,
+		UserID:    userID,
+		Username:  username,
+		ExpiresAt: now.Add(i.RefreshExpire()),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(i.config.Expire),
+		RefreshExpiresIn: int64(i.config.RefreshExpire),
+	}, nil
+}
+
+// Refresh 使用刷新token换取新的token，刷新token只能使用一次，
+// 已使用过的刷新token再次出现时撤销该登录的所有刷新token，启用会话管理时访问token同时失效
+func (i *Instance) Refresh(refreshToken string) (*TokenPair, error) {
+	if i.refresh == nil {
+		return nil, ErrRefreshDisabled
+	}
+	id := hashRefreshToken(refreshToken)
+	rt, err := i.refresh.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if util.Now().After(rt.ExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+	ok, err := i.refresh.MarkUsed(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if i.sessions != nil {
+			err = i.revokeSession(rt.UserID, rt.Family)
+		} else {
+			err = i.refresh.RevokeFamily(rt.Family)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	pair, err := i.issuePair(rt.UserID, rt.Username, rt.Family)
+	if err != nil {
+		return nil, err
+	}
+	return pair, i.touchSession(rt.Family, i.RefreshExpire())
+}
+
+// RevokeRefresh 退出登录时撤销刷新token所属登录的所有刷新token，启用会话管理时访问token同时失效
+func (i *Instance) RevokeRefresh(refreshToken string) error {
+	if i.refresh == nil {
+		return ErrRefreshDisabled
+	}
+	rt, err := i.refresh.Get(hashRefreshToken(refreshToken))
+	if errors.Is(err, ErrRefreshTokenInvalid) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if i.sessions != nil {
+		return i.revokeSession(rt.UserID, rt.Family)
+	}
+	return i.refresh.RevokeFamily(rt.Family)
+}
+
+// IssuePair 签发访问token和刷新token
+func IssuePair(userID int64, username string, opts ...TokenOption) (*TokenPair, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+	return instance.IssuePair(userID, username, opts...)
+}
+
+// Refresh 使用刷新token换取新的token
+func Refresh(refreshToken string) (*TokenPair, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+	return instance.Refresh(refreshToken)
+}
+
+// RevokeRefresh 撤销刷新token
+func RevokeRefresh(refreshToken string) error {
+	if instance == nil {
+		return ErrNotInitialized
+	}
+	return instance.RevokeRefresh(refreshToken)
+}
+
+// RedisRefreshStore redis刷新token存储，token和family都设置过期时间
+type RedisRefreshStore struct {
+	client *goredis.Client
+	prefix string
+}
+
+// NewRedisRefreshStore 创建redis刷新token存储
+func NewRedisRefreshStore(client *goredis.Client, prefix string) *RedisRefreshStore {
+	return &RedisRefreshStore{client: client, prefix: prefix}
+}
+
+func (s *RedisRefreshStore) tokenKey(id string) string {
+	return s.prefix + "refresh:" + id
+}
+
+func (s *RedisRefreshStore) familyKey(family string) string {
+	return s.prefix + "refresh_family:" + family
+}
+
+// Save 保存刷新token
+func (s *RedisRefreshStore) Save(t *RefreshToken) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(t.ExpiresAt)
+	pipe := s.client.TxPipeline()
+	pipe.HSet(s.tokenKey(t.ID), "data", string(b))
+	pipe.Expire(s.tokenKey(t.ID), ttl)
+	pipe.SAdd(s.familyKey(t.Family), t.ID)
+	pipe.Expire(s.familyKey(t.Family), ttl)
+	_, err = pipe.Exec()
+	return err
+}
+
+// Get 获取刷新token
+func (s *RedisRefreshStore) Get(id string) (*RefreshToken, error) {
+	raw, err := s.client.HGet(s.tokenKey(id), "data").Result()
+	if err == goredis.Nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := &RefreshToken{}
+	return t, json.Unmarshal([]byte(raw), t)
+}
+
+// markUsedScript token已过期或被删除时不写入，避免重新创建没有过期时间的key
+var markUsedScript = goredis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+return redis.call("HSETNX", KEYS[1], "used_at", ARGV[1])
+`)
+
+// MarkUsed 标记为已使用
+func (s *RedisRefreshStore) MarkUsed(id string) (bool, error) {
+	n, err := markUsedScript.Run(s.client, []string{s.tokenKey(id)}, util.Now().Unix()).Int64()
+	if err != nil {
+		return false, err
+	}
+	if n < 0 {
+		return false, ErrRefreshTokenInvalid
+	}
+	return n == 1, nil
+}
+
+// RevokeFamily 删除同一次登录的所有刷新token
+func (s *RedisRefreshStore) RevokeFamily(family string) error {
+	ids, err := s.client.SMembers(s.familyKey(family)).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{s.familyKey(family)}
+	for _, id := range ids {
+		keys = append(keys, s.tokenKey(id))
+	}
+	return s.client.Del(keys...).Err()
+}
+
+// GormRefreshStore 数据库刷新token存储
+type GormRefreshStore struct {
+	db *gorm.DB
+}
+
+// NewGormRefreshStore 创建数据库刷新token存储并自动建表
+func NewGormRefreshStore(db *gorm.DB) (*GormRefreshStore, error) {
+	if err := db.AutoMigrate(&RefreshToken{}); err != nil {
+		return nil, err
+	}
+	return &GormRefreshStore{db: db}, nil
+}
+
+// Save 保存刷新token
+func (s *GormRefreshStore) Save(t *RefreshToken) error {
+	return s.db.Create(t).Error
+}
+
+// Get 获取刷新token
+func (s *GormRefreshStore) Get(id string) (*RefreshToken, error) {
+	t := &RefreshToken{}
+	err := s.db.Where("id = ?", id).First(t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	return t, err
+}
+
+// MarkUsed 标记为已使用
+func (s *GormRefreshStore) MarkUsed(id string) (bool, error) {
+	tx := s.db.Model(&RefreshToken{}).Where("id = ? AND used_at IS NULL", id).Update("used_at", util.Now())
+	return tx.RowsAffected == 1, tx.Error
+}
+
+// RevokeFamily 删除同一次登录的所有刷新token
+func (s *GormRefreshStore) RevokeFamily(family string) error {
+	return s.db.Where("family = ?", family).Delete(&RefreshToken{}).Error
+}
+
+// Purge 删除已过期的刷新token
+func (s *GormRefreshStore) Purge() error {
+	return s.db.Where("expires_at < ?", util.Now()).Delete(&RefreshToken{}).Error
+}
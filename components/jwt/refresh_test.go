@@ -0,0 +1,161 @@
+package jwt
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis"
+	"github.com/hoorayui/core-framework/types"
+)
+
+// memoryRefreshStore 测试用的内存存储
+type memoryRefreshStore struct {
+	l      sync.Mutex
+	tokens map[string]*RefreshToken
+}
+
+func (s *memoryRefreshStore) Save(t *RefreshToken) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.tokens[t.ID] = t
+	return nil
+}
+
+func (s *memoryRefreshStore) Get(id string) (*RefreshToken, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	t, ok := s.tokens[id]
+	if !ok {
+		return nil, ErrRefreshTokenInvalid
+	}
+	return t, nil
+}
+
+func (s *memoryRefreshStore) MarkUsed(id string) (bool, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	t, ok := s.tokens[id]
+	if !ok {
+		return false, ErrRefreshTokenInvalid
+	}
+	if t.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	return true, nil
+}
+
+func (s *memoryRefreshStore) RevokeFamily(family string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	for id, t := range s.tokens {
+		if t.Family == family {
+			delete(s.tokens, id)
+		}
+	}
+	return nil
+}
+
+func TestRefreshRotation(t *testing.T) {
+	i, err := New(types.JWTConfig{Secret: "test-secret-0123456789abcdefghijklmnop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.SetRefreshStore(&memoryRefreshStore{tokens: map[string]*RefreshToken{}})
+
+	first, err := i.IssuePair(1, "张三")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := i.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("刷新后应轮换刷新token")
+	}
+	if claims, err := i.ParseToken(second.AccessToken); err != nil || claims.UserID != 1 {
+		t.Fatalf("新的访问token无效:%v", err)
+	}
+	// 旧的刷新token再次使用，整个登录被撤销
+	if _, err := i.Refresh(first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("期望重复使用错误，实际%v", err)
+	}
+	if _, err := i.Refresh(second.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("撤销后刷新token应失效，实际%v", err)
+	}
+}
+
+// newSessionInstance 创建启用会话管理的实例，redis使用miniredis
+func newSessionInstance(t *testing.T) (*Instance, *miniredis.Miniredis) {
+	t.Helper()
+	i, err := New(types.JWTConfig{Secret: "test-secret-0123456789abcdefghijklmnop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	i.SetRefreshStore(&memoryRefreshStore{tokens: map[string]*RefreshToken{}})
+	i.sessions = NewSessionStore(client, "jwt:")
+	return i, mr
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	i, _ := newSessionInstance(t)
+	first, err := i.IssuePair(1, "张三")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := i.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.ParseToken(first.AccessToken); err != nil {
+		t.Fatalf("刷新后之前的访问token在有效期内仍可使用:%v", err)
+	}
+
+	if _, err := i.Refresh(first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("期望重复使用错误，实际%v", err)
+	}
+	for _, access := range []string{first.AccessToken, second.AccessToken} {
+		if _, err := i.ParseToken(access); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("重复使用刷新token后该登录的访问token应失效，实际%v", err)
+		}
+	}
+	if list, _ := i.Sessions(1); len(list) != 0 {
+		t.Errorf("重复使用刷新token后应删除会话:%d", len(list))
+	}
+}
+
+func TestRedisRefreshStoreMarkUsed(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	s := NewRedisRefreshStore(client, "jwt:")
+	if err := s.Save(&RefreshToken{ID: "t1", Family: "f1", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.MarkUsed("t1"); err != nil || !ok {
+		t.Fatalf("第一次使用:%v %v", ok, err)
+	}
+	if ok, err := s.MarkUsed("t1"); err != nil || ok {
+		t.Fatalf("重复使用应返回false:%v %v", ok, err)
+	}
+	if ttl := mr.TTL(s.tokenKey("t1")); ttl <= 0 {
+		t.Errorf("标记后应保留过期时间:%s", ttl)
+	}
+
+	// 已过期或被删除的token不应重新创建
+	mr.FastForward(2 * time.Hour)
+	if ok, err := s.MarkUsed("t1"); !errors.Is(err, ErrRefreshTokenInvalid) || ok {
+		t.Errorf("已过期的token:%v %v", ok, err)
+	}
+	if mr.Exists(s.tokenKey("t1")) {
+		t.Error("标记已过期的token时不应重新创建没有过期时间的key")
+	}
+}
@@ -0,0 +1,345 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+	"github.com/hoorayui/core-framework/util"
+)
+
+// 会话错误
+var (
+	ErrSessionsDisabled = errors.New("未启用会话管理")
+	ErrSessionNotFound  = errors.New("会话不存在")
+)
+
+// Session 登录会话，GenToken签发的每个token为一个会话，IssuePair的一次登录为一个会话
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Device    string    `json:"device"`
+	IP        string    `json:"ip"`
+	IssuedAt  time.Time `json:"issued_at"`
+	LastSeen  time.Time `json:"last_seen"` // 最近一次刷新的时间
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionStore redis会话存储，同时保存按jti撤销的token、撤销的会话和用户的撤销时间
+type SessionStore struct {
+	client *goredis.Client
+	prefix string
+}
+
+// NewSessionStore 创建会话存储
+func NewSessionStore(client *goredis.Client, prefix string) *SessionStore {
+	return &SessionStore{client: client, prefix: prefix}
+}
+
+func (s *SessionStore) sessionKey(sid string) string {
+	return s.prefix + "session:" + sid
+}
+
+func (s *SessionStore) userKey(userID int64) string {
+	return s.prefix + "sessions:" + strconv.FormatInt(userID, 10)
+}
+
+func (s *SessionStore) revokedKey(jti string) string {
+	return s.prefix + "revoked:" + jti
+}
+
+func (s *SessionStore) sessionRevokedKey(sid string) string {
+	return s.prefix + "session_revoked:" + sid
+}
+
+func (s *SessionStore) watermarkKey(userID int64) string {
+	return s.prefix + "watermark:" + strconv.FormatInt(userID, 10)
+}
+
+// Save 保存会话，用户的会话按过期时间保存在有序集合中
+func (s *SessionStore) Save(sess *Session) error {
+	b, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(sess.ExpiresAt)
+	pipe := s.client.TxPipeline()
+	pipe.Set(s.sessionKey(sess.ID), string(b), ttl)
+	pipe.ZAdd(s.userKey(sess.UserID), goredis.Z{Score: float64(sess.ExpiresAt.Unix()), Member: sess.ID})
+	// 用户的会话集合在最后一个会话过期后删除
+	pipe.ExpireAt(s.userKey(sess.UserID), sess.ExpiresAt)
+	_, err = pipe.Exec()
+	return err
+}
+
+// Get 获取会话
+func (s *SessionStore) Get(sid string) (*Session, error) {
+	raw, err := s.client.Get(s.sessionKey(sid)).Result()
+	if err == goredis.Nil {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	sess := &Session{}
+	return sess, json.Unmarshal([]byte(raw), sess)
+}
+
+// List 用户未过期的会话，按签发时间排序
+func (s *SessionStore) List(userID int64) ([]*Session, error) {
+	key := s.userKey(userID)
+	now := strconv.FormatInt(util.Now().Unix(), 10)
+	if err := s.client.ZRemRangeByScore(key, "-inf", "("+now).Err(); err != nil {
+		return nil, err
+	}
+	ids, err := s.client.ZRange(key, 0, -1).Result()
+	if err != nil || len(ids) == 0 {
+		return []*Session{}, err
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, s.sessionKey(id))
+	}
+	values, err := s.client.MGet(keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Session, 0, len(values))
+	for _, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			continue
+		}
+		sess := &Session{}
+		if err := json.Unmarshal([]byte(raw), sess); err == nil {
+			list = append(list, sess)
+		}
+	}
+	for a := 1; a < len(list); a++ {
+		for b := a; b > 0 && list[b].IssuedAt.Before(list[b-1].IssuedAt); b-- {
+			list[b], list[b-1] = list[b-1], list[b]
+		}
+	}
+	return list, nil
+}
+
+// Delete 删除会话
+func (s *SessionStore) Delete(userID int64, sid string) error {
+	pipe := s.client.TxPipeline()
+	pipe.Del(s.sessionKey(sid))
+	pipe.ZRem(s.userKey(userID), sid)
+	_, err := pipe.Exec()
+	return err
+}
+
+// RevokeJTI 撤销单个token，ttl为token的剩余有效期
+func (s *SessionStore) RevokeJTI(jti string, ttl time.Duration) error {
+	return s.client.Set(s.revokedKey(jti), 1, ttl).Err()
+}
+
+// RevokeSession 撤销会话的所有token，ttl为访问token的最长有效期
+func (s *SessionStore) RevokeSession(sid string, ttl time.Duration) error {
+	return s.client.Set(s.sessionRevokedKey(sid), 1, ttl).Err()
+}
+
+// SetWatermark 早于t签发的token全部失效
+func (s *SessionStore) SetWatermark(userID int64, t time.Time, ttl time.Duration) error {
+	return s.client.Set(s.watermarkKey(userID), t.Unix(), ttl).Err()
+}
+
+// Revoked 判断token是否已撤销
+func (s *SessionStore) Revoked(jti, sid string, userID, issuedAt int64) (bool, error) {
+	pipe := s.client.Pipeline()
+	jtiCmd := pipe.Exists(s.revokedKey(jti))
+	sidCmd := pipe.Exists(s.sessionRevokedKey(sid))
+	watermarkCmd := pipe.Get(s.watermarkKey(userID))
+	if _, err := pipe.Exec(); err != nil && err != goredis.Nil {
+		return false, err
+	}
+	if jtiCmd.Val() > 0 || (sid != "" && sidCmd.Val() > 0) {
+		return true, nil
+	}
+	watermark, err := watermarkCmd.Int64()
+	if err == goredis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return issuedAt < watermark, nil
+}
+
+// saveSession 记录新的会话
+func (i *Instance) saveSession(userID int64, username, sid string, ttl time.Duration, opts []TokenOption) error {
+	if i.sessions == nil {
+		return nil
+	}
+	now := util.Now()
+	sess := &Session{
+		ID:        sid,
+		UserID:    userID,
+		Username:  username,
+		IssuedAt:  now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	for _, opt := range opts {
+		opt(sess)
+	}
+	return i.sessions.Save(sess)
+}
+
+// touchSession 刷新token时延长会话
+func (i *Instance) touchSession(sid string, ttl time.Duration) error {
+	if i.sessions == nil {
+		return nil
+	}
+	sess, err := i.sessions.Get(sid)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	now := util.Now()
+	sess.LastSeen = now
+	sess.ExpiresAt = now.Add(ttl)
+	return i.sessions.Save(sess)
+}
+
+// checkRevoked 校验token未被撤销
+func (i *Instance) checkRevoked(claims CustomClaims) error {
+	if i.sessions == nil {
+		return nil
+	}
+	var userID int64
+	var sid string
+	if sc, ok := claims.(sessionClaims); ok {
+		userID, sid = sc.session()
+	}
+	std := claims.Registered()
+	revoked, err := i.sessions.Revoked(std.Id, sid, userID, std.IssuedAt)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// maxTokenLife 访问token的最长剩余有效期
+func (i *Instance) maxTokenLife() time.Duration {
+	return time.Duration(i.config.Expire+i.config.Leeway) * time.Second
+}
+
+// RevokeToken 撤销单个token，已过期的token不需要撤销
+func (i *Instance) RevokeToken(tokenString string) error {
+	if i.sessions == nil {
+		return ErrSessionsDisabled
+	}
+	mc, err := i.parse(tokenString)
+	if mc == nil {
+		return err
+	}
+	ttl := time.Until(time.Unix(mc.ExpiresAt+int64(i.config.Leeway), 0))
+	if ttl <= 0 {
+		return nil
+	}
+	return i.sessions.RevokeJTI(mc.Id, ttl)
+}
+
+// Sessions 用户未过期的会话
+func (i *Instance) Sessions(userID int64) ([]*Session, error) {
+	if i.sessions == nil {
+		return nil, ErrSessionsDisabled
+	}
+	return i.sessions.List(userID)
+}
+
+// RevokeSession 撤销用户的一个会话，会话的访问token和刷新token都失效。
+// 会话不存在或不属于该用户时返回ErrSessionNotFound
+func (i *Instance) RevokeSession(userID int64, sid string) error {
+	if i.sessions == nil {
+		return ErrSessionsDisabled
+	}
+	sess, err := i.sessions.Get(sid)
+	if err != nil {
+		return err
+	}
+	if sess.UserID != userID {
+		return ErrSessionNotFound
+	}
+	return i.revokeSession(userID, sid)
+}
+
+// revokeSession 撤销会话，不校验会话是否属于该用户
+func (i *Instance) revokeSession(userID int64, sid string) error {
+	if err := i.sessions.RevokeSession(sid, i.maxTokenLife()); err != nil {
+		return err
+	}
+	if err := i.sessions.Delete(userID, sid); err != nil {
+		return err
+	}
+	if i.refresh != nil {
+		return i.refresh.RevokeFamily(sid)
+	}
+	return nil
+}
+
+// RevokeUser 撤销用户当前所有的token，用于退出所有设备和禁用用户
+func (i *Instance) RevokeUser(userID int64) error {
+	if i.sessions == nil {
+		return ErrSessionsDisabled
+	}
+	ttl := i.maxTokenLife() + i.RefreshExpire()
+	if err := i.sessions.SetWatermark(userID, util.Now(), ttl); err != nil {
+		return err
+	}
+	list, err := i.sessions.List(userID)
+	if err != nil {
+		return err
+	}
+	// 撤销时间精确到秒，同一秒内签发的token通过会话撤销
+	for _, sess := range list {
+		if err := i.revokeSession(userID, sess.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeToken 撤销单个token
+func RevokeToken(tokenString string) error {
+	if instance == nil {
+		return ErrNotInitialized
+	}
+	return instance.RevokeToken(tokenString)
+}
+
+// Sessions 用户未过期的会话
+func Sessions(userID int64) ([]*Session, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+	return instance.Sessions(userID)
+}
+
+// RevokeSession 撤销用户的一个会话
+func RevokeSession(userID int64, sid string) error {
+	if instance == nil {
+		return ErrNotInitialized
+	}
+	return instance.RevokeSession(userID, sid)
+}
+
+// RevokeUser 撤销用户当前所有的token
+func RevokeUser(userID int64) error {
+	if instance == nil {
+		return ErrNotInitialized
+	}
+	return instance.RevokeUser(userID)
+}
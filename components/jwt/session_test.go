@@ -0,0 +1,185 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hoorayui/core-framework/types"
+)
+
+func TestSessionStoreRevoked(t *testing.T) {
+	i, _ := newSessionInstance(t)
+	s := i.sessions
+	if revoked, err := s.Revoked("jti-1", "sid-1", 1, 100); err != nil || revoked {
+		t.Fatalf("未撤销的token:%v %v", revoked, err)
+	}
+	s.RevokeJTI("jti-1", time.Minute)
+	s.RevokeSession("sid-1", time.Minute)
+	s.SetWatermark(1, time.Unix(100, 0), time.Minute)
+	cases := []struct {
+		jti, sid string
+		userID   int64
+		issuedAt int64
+		want     bool
+	}{
+		{"jti-1", "", 2, 100, true},
+		{"jti-2", "sid-1", 2, 100, true},
+		{"jti-2", "", 2, 100, false},
+		{"jti-2", "sid-2", 1, 99, true},
+		{"jti-2", "sid-2", 1, 100, false},
+	}
+	for _, c := range cases {
+		if revoked, err := s.Revoked(c.jti, c.sid, c.userID, c.issuedAt); err != nil || revoked != c.want {
+			t.Errorf("%+v:实际%v %v", c, revoked, err)
+		}
+	}
+}
+
+func TestSessionStoreList(t *testing.T) {
+	i, mr := newSessionInstance(t)
+	s := i.sessions
+	now := time.Now()
+	for _, sess := range []*Session{
+		{ID: "b", UserID: 1, IssuedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+		{ID: "a", UserID: 1, IssuedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{ID: "c", UserID: 2, IssuedAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := s.Save(sess); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 已过期的会话
+	mr.ZAdd(s.userKey(1), float64(now.Add(-time.Second).Unix()), "expired")
+
+	list, err := s.List(1)
+	if err != nil || len(list) != 2 || list[0].ID != "a" || list[1].ID != "b" {
+		t.Fatalf("会话列表应按签发时间排序并去掉过期的会话:%v %v", list, err)
+	}
+	if members, _ := mr.ZMembers(s.userKey(1)); len(members) != 2 {
+		t.Errorf("查询时应删除过期的会话:%v", members)
+	}
+	if err := s.Delete(1, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("a"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("删除后会话应不存在:%v", err)
+	}
+	if list, _ := s.List(1); len(list) != 1 || list[0].ID != "b" {
+		t.Errorf("删除后的会话列表:%v", list)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	i, _ := newSessionInstance(t)
+	first, _ := i.GenToken(1, "张三", WithClient("iPhone", "127.0.0.1"))
+	second, _ := i.GenToken(1, "张三")
+	list, err := i.Sessions(1)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("每个token为一个会话:%v %v", list, err)
+	}
+	if list[0].Device != "iPhone" || list[0].IP != "127.0.0.1" {
+		t.Errorf("会话未记录设备信息:%+v", list[0])
+	}
+
+	if err := i.RevokeToken(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.ParseToken(first); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("撤销后token应失效:%v", err)
+	}
+	if _, err := i.ParseToken(second); err != nil {
+		t.Errorf("其他token不应失效:%v", err)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	i, _ := newSessionInstance(t)
+	phone, _ := i.IssuePair(1, "张三")
+	desktop, _ := i.IssuePair(1, "张三")
+	claims, err := i.ParseToken(phone.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sid := claims.session()
+
+	if err := i.RevokeSession(1, sid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.ParseToken(phone.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("撤销会话后访问token应失效:%v", err)
+	}
+	if _, err := i.Refresh(phone.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("撤销会话后刷新token应失效:%v", err)
+	}
+	if _, err := i.ParseToken(desktop.AccessToken); err != nil {
+		t.Errorf("其他会话不应失效:%v", err)
+	}
+	if list, _ := i.Sessions(1); len(list) != 1 || list[0].ID == sid {
+		t.Errorf("撤销后的会话列表:%v", list)
+	}
+}
+
+func TestRevokeOtherUserSession(t *testing.T) {
+	i, _ := newSessionInstance(t)
+	pair, _ := i.IssuePair(1, "张三")
+	claims, _ := i.ParseToken(pair.AccessToken)
+	_, sid := claims.session()
+
+	if err := i.RevokeSession(2, sid); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("不能撤销其他用户的会话:%v", err)
+	}
+	if _, err := i.ParseToken(pair.AccessToken); err != nil {
+		t.Errorf("其他用户撤销失败后token应有效:%v", err)
+	}
+	if _, err := i.Refresh(pair.RefreshToken); err != nil {
+		t.Errorf("其他用户撤销失败后刷新token应有效:%v", err)
+	}
+	if err := i.RevokeSession(1, "none"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("会话不存在:%v", err)
+	}
+}
+
+func TestRevokeUser(t *testing.T) {
+	i, _ := newSessionInstance(t)
+	pair, _ := i.IssuePair(1, "张三")
+	token, _ := i.GenToken(1, "张三")
+	other, _ := i.GenToken(2, "李四")
+
+	if err := i.RevokeUser(1); err != nil {
+		t.Fatal(err)
+	}
+	for _, str := range []string{pair.AccessToken, token} {
+		if _, err := i.ParseToken(str); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("撤销用户后token应失效:%v", err)
+		}
+	}
+	if _, err := i.Refresh(pair.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("撤销用户后刷新token应失效:%v", err)
+	}
+	if _, err := i.ParseToken(other); err != nil {
+		t.Errorf("其他用户不应失效:%v", err)
+	}
+	if list, _ := i.Sessions(1); len(list) != 0 {
+		t.Errorf("撤销用户后应删除所有会话:%v", list)
+	}
+	// 撤销后重新登录
+	relogin, _ := i.IssuePair(1, "张三")
+	if _, err := i.ParseToken(relogin.AccessToken); err != nil {
+		t.Errorf("撤销后重新登录的token应有效:%v", err)
+	}
+}
+
+func TestSessionsDisabled(t *testing.T) {
+	i, err := New(types.JWTConfig{Secret: "test-secret-0123456789abcdefghijklmnop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := i.GenToken(1, "张三")
+	if err := i.RevokeToken(token); !errors.Is(err, ErrSessionsDisabled) {
+		t.Errorf("未启用会话管理:%v", err)
+	}
+	if _, err := i.Sessions(1); !errors.Is(err, ErrSessionsDisabled) {
+		t.Errorf("未启用会话管理:%v", err)
+	}
+}
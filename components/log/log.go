@@ -2,9 +2,13 @@ package log
 
 // log
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/hoorayui/core-framework/util"
 	"github.com/hoorayui/core-framework/util/flag"
@@ -28,16 +32,25 @@ func (u UTCFormatter) Format(e *logrus.Entry) ([]byte, error) {
 	return u.Formatter.Format(e)
 }
 
-// GetMultiWriter 设置日志写入流
+// GetMultiWriter 日志写入流，同时写入标准输出和日志文件
 func GetMultiWriter() io.Writer {
-	// TODO 替换为应用名称
-	path := fmt.Sprintf("%s/app.log", flag.LogDir)
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0777)
+	if instance.writer != nil {
+		return instance.writer
+	}
+	// 日志组件未加载时使用启动参数log-dir
+	name := flag.APPNAME + ".log"
+	f, err := instance.OpenWriter(name)
 	if nil != err {
-		logrus.Fatal("打开日志文件[%s]失败", path, flag.LogDir)
+		logrus.Fatalf("打开日志文件[%s]失败", name)
 		return nil
 	}
-	return io.MultiWriter(os.Stdout, f)
+	instance.writer = io.MultiWriter(os.Stdout, f)
+	return instance.writer
+}
+
+// WithFields 结构化日志
+func WithFields(fields logrus.Fields) *logrus.Entry {
+	return instance.logger.WithFields(fields)
 }
 
 func Debug(args ...interface{}) {
@@ -88,14 +101,49 @@ func Tracef(format string, args ...interface{}) {
 	instance.logger.Tracef(format, args...)
 }
 
-// ErrorHook ...
-type ErrorHook struct{}
+// ErrorHook 将error及以上级别的日志另外写入错误日志文件
+type ErrorHook struct {
+	writer    io.Writer
+	formatter logrus.Formatter
+}
 
 func (h *ErrorHook) Levels() []logrus.Level {
-	return logrus.AllLevels
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
 }
 
 func (h *ErrorHook) Fire(entry *logrus.Entry) error {
-	// entry.Logger.
-	return nil
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.writer.Write(b)
+	return err
+}
+
+// TextFormatter 便于阅读的文本格式：时间 [级别] 消息 key=value
+type TextFormatter struct {
+	TimestampFormat string
+}
+
+// Format 格式化日志
+func (f *TextFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	b := &bytes.Buffer{}
+	b.WriteString(e.Time.Format(f.TimestampFormat))
+	b.WriteString(" [")
+	b.WriteString(strings.ToUpper(e.Level.String()))
+	b.WriteString("] ")
+	if e.HasCaller() {
+		fmt.Fprintf(b, "%s:%d ", filepath.Base(e.Caller.File), e.Caller.Line)
+	}
+	b.WriteString(e.Message)
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, " %s=%v", k, e.Data[k])
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
 }
@@ -2,15 +2,40 @@ package log
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/hoorayui/core-framework/types"
+	"github.com/hoorayui/core-framework/util/flag"
 	"github.com/sirupsen/logrus"
 )
 
+// 日志格式
+const (
+	FormatJSON   = "json"
+	FormatText   = "text"
+	FormatLogfmt = "logfmt"
+)
+
+// RotateExternal 由外部logrotate轮转日志
+const RotateExternal = "external"
+
 type Instance struct {
-	config types.LogConfig
-	logger *logrus.Logger
+	config  types.LogConfig
+	logger  *logrus.Logger
+	level   logrus.Level
+	writer  io.Writer // 标准输出和日志文件
+	l       sync.Mutex
+	files   []*RotateWriter
+	signals chan os.Signal
 }
 
 var instance *Instance
@@ -28,35 +53,171 @@ func (i *Instance) GetName() string {
 
 // Init 初始化实例
 func (i *Instance) Init(config interface{}) error {
-	instance = i
 	bytes, _ := json.Marshal(config)
 	json.Unmarshal(bytes, &i.config)
-	i.Validate()
+	if err := i.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(i.config.LogDir, 0755); err != nil {
+		return fmt.Errorf("创建日志目录[%s]失败:%w", i.config.LogDir, err)
+	}
+	f, err := i.OpenWriter(i.config.LogFileName)
+	if err != nil {
+		return err
+	}
+	i.writer = io.MultiWriter(os.Stdout, f)
 
 	i.logger = logrus.New()
 	i.logger.SetReportCaller(true)
 	i.logger.SetNoLock()
-	i.logger.SetOutput(GetMultiWriter())
-	i.logger.Formatter = &UTCFormatter{&logrus.JSONFormatter{
-		TimestampFormat: time.DateTime,
-		PrettyPrint:     false,
-	}}
+	i.logger.SetOutput(i.writer)
+	i.logger.Formatter = &UTCFormatter{newFormatter(i.config.LogFormat)}
+	i.logger.SetLevel(i.level)
+	if i.config.ErrorLogFileName != "" {
+		ef, err := i.OpenWriter(i.config.ErrorLogFileName)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		i.logger.AddHook(&ErrorHook{writer: ef, formatter: i.logger.Formatter})
+	}
+	if i.config.LogRotate == RotateExternal {
+		i.signals = make(chan os.Signal, 1)
+		signal.Notify(i.signals, syscall.SIGHUP)
+		go i.reopen(i.signals)
+	}
+	prev := instance
+	instance = i
+	// 重新初始化时关闭之前实例打开的日志文件
+	if prev != nil && prev != i {
+		prev.Close()
+	}
+	return nil
+}
 
-	i.logger.SetLevel(logrus.InfoLevel)
+// OpenWriter 打开日志目录下的文件，按配置轮转，关闭组件时关闭
+func (i *Instance) OpenWriter(name string) (*RotateWriter, error) {
+	dir := i.config.LogDir
+	if dir == "" {
+		dir = flag.LogDir
+	}
+	config := RotateConfig{}
+	if i.config.LogRotate != RotateExternal {
+		config = RotateConfig{
+			MaxSize:     int64(i.config.LogMaxSize) << 20,
+			Daily:       i.config.LogRotateDaily,
+			Compress:    i.config.LogCompress,
+			ReserveDays: i.config.LogReserveDays,
+		}
+	}
+	w, err := NewRotateWriter(filepath.Join(dir, name), config)
+	if err != nil {
+		return nil, err
+	}
+	i.l.Lock()
+	i.files = append(i.files, w)
+	i.l.Unlock()
+	return w, nil
+}
 
-	return nil
+// reopen 收到SIGHUP时重新打开所有日志文件
+func (i *Instance) reopen(signals chan os.Signal) {
+	for range signals {
+		i.l.Lock()
+		for _, w := range i.files {
+			if err := w.Reopen(); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+			}
+		}
+		i.l.Unlock()
+	}
 }
 
-// Validate 验证配置
+// Validate 验证配置，日志目录为空时使用启动参数log-dir，文件名为空时使用应用名称
 func (i *Instance) Validate() error {
+	if i.config.LogLevel == "" {
+		i.config.LogLevel = logrus.InfoLevel.String()
+	}
+	level, err := logrus.ParseLevel(i.config.LogLevel)
+	if err != nil {
+		return fmt.Errorf("不支持的日志级别[%s]", i.config.LogLevel)
+	}
+	i.level = level
+	i.config.LogFormat = strings.ToLower(i.config.LogFormat)
+	switch i.config.LogFormat {
+	case "":
+		i.config.LogFormat = FormatJSON
+	case FormatJSON, FormatText, FormatLogfmt:
+	default:
+		return fmt.Errorf("不支持的日志格式[%s]，可选json、text、logfmt", i.config.LogFormat)
+	}
+	if i.config.LogDir == "" {
+		i.config.LogDir = flag.LogDir
+	}
+	if i.config.LogFileName == "" {
+		i.config.LogFileName = flag.APPNAME + ".log"
+	}
+	if i.config.LogMaxSize < 0 || i.config.LogReserveDays < 0 {
+		return errors.New("日志文件大小和保留天数不能小于0")
+	}
+	switch i.config.LogRotate {
+	case "", RotateExternal:
+	default:
+		return fmt.Errorf("不支持的日志轮转方式[%s]，可选external", i.config.LogRotate)
+	}
+	for _, name := range []string{i.config.LogFileName, i.config.ErrorLogFileName} {
+		if strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("日志文件名[%s]不能包含路径，目录使用log_dir配置", name)
+		}
+	}
 	return nil
 }
 
+// newFormatter 按配置的格式创建formatter
+func newFormatter(format string) logrus.Formatter {
+	switch format {
+	case FormatText:
+		return &TextFormatter{TimestampFormat: time.DateTime}
+	case FormatLogfmt:
+		return &logrus.TextFormatter{
+			DisableColors:   true,
+			FullTimestamp:   true,
+			TimestampFormat: time.DateTime,
+		}
+	default:
+		return &logrus.JSONFormatter{
+			TimestampFormat: time.DateTime,
+			PrettyPrint:     false,
+		}
+	}
+}
+
 // GetInstance 获取实例
 func GetInstance() *Instance {
 	return instance
 }
 
+// Logger 日志实例
+func (i *Instance) Logger() *logrus.Logger {
+	return i.logger
+}
+
+// NewFileWriter 打开日志目录下的其他日志文件，与应用日志使用相同的轮转配置
+func NewFileWriter(name string) (io.Writer, error) {
+	return instance.OpenWriter(name)
+}
+
 // Close 关闭
 func (i *Instance) Close() {
+	if i.signals != nil {
+		signal.Stop(i.signals)
+		close(i.signals)
+		i.signals = nil
+	}
+	i.l.Lock()
+	defer i.l.Unlock()
+	for _, f := range i.files {
+		f.Close()
+	}
+	i.files = nil
 }
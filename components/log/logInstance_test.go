@@ -0,0 +1,123 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hoorayui/core-framework/types"
+	"github.com/hoorayui/core-framework/util/flag"
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidateDefault(t *testing.T) {
+	i := &Instance{}
+	if err := i.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if i.level != logrus.InfoLevel || i.config.LogLevel != "info" {
+		t.Errorf("默认日志级别:%s", i.config.LogLevel)
+	}
+	if i.config.LogFormat != FormatJSON {
+		t.Errorf("默认日志格式:%s", i.config.LogFormat)
+	}
+	if i.config.LogDir != flag.LogDir || i.config.LogFileName != flag.APPNAME+".log" {
+		t.Errorf("默认日志文件:%s %s", i.config.LogDir, i.config.LogFileName)
+	}
+
+	i = &Instance{config: types.LogConfig{LogLevel: "debug", LogFormat: "TEXT"}}
+	if err := i.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if i.level != logrus.DebugLevel || i.config.LogFormat != FormatText {
+		t.Errorf("日志级别和格式:%s %s", i.level, i.config.LogFormat)
+	}
+}
+
+func TestValidateInvalid(t *testing.T) {
+	cases := map[string]types.LogConfig{
+		"未知的日志级别":     {LogLevel: "verbose"},
+		"未知的日志格式":     {LogFormat: "xml"},
+		"文件名包含路径":     {LogFileName: "../app.log"},
+		"文件名包含反斜杠":    {LogFileName: `logs\app.log`},
+		"错误日志文件名包含路径": {ErrorLogFileName: "/var/log/error.log"},
+		"文件大小小于0":     {LogMaxSize: -1},
+		"保留天数小于0":     {LogReserveDays: -1},
+		"未知的轮转方式":     {LogRotate: "cron"},
+	}
+	for name, config := range cases {
+		i := &Instance{config: config}
+		if err := i.Validate(); err == nil {
+			t.Errorf("%s:应返回错误", name)
+		}
+	}
+}
+
+// initInstance 初始化日志组件，测试结束后关闭并恢复之前的全局实例
+func initInstance(t *testing.T, config types.LogConfig) *Instance {
+	t.Helper()
+	prev := instance
+	i := &Instance{}
+	if err := i.Init(config); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		i.Close()
+		instance = prev
+	})
+	return i
+}
+
+func TestInitClosesPrevious(t *testing.T) {
+	dir := t.TempDir()
+	first := initInstance(t, types.LogConfig{LogDir: dir, LogFileName: "first.log", ErrorLogFileName: "first-error.log"})
+	files := first.files
+	if len(files) != 2 {
+		t.Fatalf("打开的日志文件:%d", len(files))
+	}
+	initInstance(t, types.LogConfig{LogDir: dir, LogFileName: "second.log"})
+	if GetInstance() == first || first.files != nil {
+		t.Fatal("重新初始化后应关闭之前实例的日志文件")
+	}
+	for _, f := range files {
+		if _, err := f.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("之前实例的日志文件应已关闭:%v", err)
+		}
+	}
+}
+
+func TestInitErrorLogFailed(t *testing.T) {
+	dir := t.TempDir()
+	// 错误日志文件名与目录重名，打开失败
+	os.Mkdir(filepath.Join(dir, "error.log"), 0755)
+	i := &Instance{}
+	if err := i.Init(types.LogConfig{LogDir: dir, LogFileName: "app.log", ErrorLogFileName: "error.log"}); err == nil {
+		t.Fatal("错误日志文件打开失败时应返回错误")
+	}
+	if len(i.files) != 1 {
+		t.Fatalf("打开的日志文件:%d", len(i.files))
+	}
+	if _, err := i.files[0].Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("初始化失败时应关闭已打开的日志文件:%v", err)
+	}
+}
+
+func TestSignalReopen(t *testing.T) {
+	dir := t.TempDir()
+	i := initInstance(t, types.LogConfig{LogDir: dir, LogFileName: "app.log", LogRotate: RotateExternal})
+	path := filepath.Join(dir, "app.log")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	i.signals <- syscall.SIGHUP
+	deadline := time.Now().Add(time.Second)
+	for !exists(path) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !exists(path) {
+		t.Fatal("收到SIGHUP后应重新打开日志文件")
+	}
+}
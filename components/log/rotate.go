@@ -0,0 +1,232 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hoorayui/core-framework/util"
+	"github.com/sirupsen/logrus"
+)
+
+// RotateConfig 日志文件轮转配置
+type RotateConfig struct {
+	MaxSize     int64 // 单个文件最大字节数，0为不按大小轮转
+	Daily       bool  // 每天轮转
+	Compress    bool  // gzip压缩轮转后的文件
+	ReserveDays int   // 轮转后的文件保留天数，0为永久保留
+}
+
+// RotateWriter 按大小和日期轮转的日志文件，轮转后的文件名为name-20060102-150405.ext，
+// 时间为文件最后写入的时间
+type RotateWriter struct {
+	path   string
+	config RotateConfig
+
+	l    sync.Mutex
+	file *os.File
+	size int64
+	day  string // 当前文件最后写入的日期
+	wg   sync.WaitGroup
+}
+
+// NewRotateWriter 打开日志文件，启动时清理过期的文件
+func NewRotateWriter(path string, config RotateConfig) (*RotateWriter, error) {
+	w := &RotateWriter{path: path, config: config}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.cleanup()
+	}()
+	return w, nil
+}
+
+// openFile 打开日志文件，测试时替换以模拟打开失败
+var openFile = os.OpenFile
+
+// open 打开新的日志文件，成功后关闭原文件并替换，失败时继续使用原文件
+func (w *RotateWriter) open() error {
+	f, err := openFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件[%s]失败:%w", w.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file = f
+	w.size = info.Size()
+	w.day = util.Now().Format("2006-01-02")
+	if w.size > 0 {
+		w.day = info.ModTime().In(util.Now().Location()).Format("2006-01-02")
+	}
+	return nil
+}
+
+// Write 写入日志，超过大小或跨天时先轮转
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.l.Lock()
+	defer w.l.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	today := util.Now().Format("2006-01-02")
+	if w.size > 0 && ((w.config.MaxSize > 0 && w.size+int64(len(p)) > w.config.MaxSize) ||
+		(w.config.Daily && w.day != today)) {
+		if err := w.rotate(); err != nil {
+			// 轮转失败时继续写入原文件，避免丢失日志
+			fmt.Fprintf(os.Stderr, "日志文件[%s]轮转失败:%s\n", w.path, err.Error())
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	w.day = today
+	return n, err
+}
+
+// Rotate 立即轮转
+func (w *RotateWriter) Rotate() error {
+	w.l.Lock()
+	defer w.l.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+func (w *RotateWriter) rotate() error {
+	info, err := w.file.Stat()
+	if err != nil {
+		return err
+	}
+	backup := w.backupName(info.ModTime())
+	if err := os.Rename(w.path, backup); err != nil {
+		return err
+	}
+	// 打开新文件失败时移回原文件，继续写入原文件
+	if err := w.open(); err != nil {
+		if renameErr := os.Rename(backup, w.path); renameErr != nil {
+			logrus.Errorf("日志文件[%s]移回[%s]失败:%s", backup, w.path, renameErr.Error())
+		}
+		return err
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if w.config.Compress {
+			if err := compress(backup); err != nil {
+				logrus.Errorf("压缩日志文件[%s]失败:%s", backup, err.Error())
+			}
+		}
+		w.cleanup()
+	}()
+	return nil
+}
+
+// backupName 轮转后的文件名，同一秒内多次轮转时加序号
+func (w *RotateWriter) backupName(t time.Time) string {
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(w.path, ext) + "-" + t.In(util.Now().Location()).Format("20060102-150405")
+	name := prefix + ext
+	for n := 1; exists(name) || exists(name+".gz"); n++ {
+		name = fmt.Sprintf("%s.%d%s", prefix, n, ext)
+	}
+	return name
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compress gzip压缩文件并删除原文件，压缩文件保留原文件的修改时间用于清理
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+	return os.Remove(path)
+}
+
+// cleanup 删除超过保留天数的轮转文件
+func (w *RotateWriter) cleanup() {
+	if w.config.ReserveDays <= 0 {
+		return
+	}
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext)
+	files, err := filepath.Glob(base + "-*")
+	if err != nil {
+		return
+	}
+	// 只匹配轮转生成的文件名，避免删除名称相近的其他日志
+	backup := regexp.MustCompile(`^` + regexp.QuoteMeta(base) + `-\d{8}-\d{6}(\.\d+)?` + regexp.QuoteMeta(ext) + `(\.gz)?$`)
+	deadline := util.Now().AddDate(0, 0, -w.config.ReserveDays)
+	for _, f := range files {
+		if !backup.MatchString(f) {
+			continue
+		}
+		info, err := os.Stat(f)
+		if err != nil || info.IsDir() || info.ModTime().After(deadline) {
+			continue
+		}
+		if err := os.Remove(f); err != nil {
+			logrus.Errorf("删除过期日志文件[%s]失败:%s", f, err.Error())
+		}
+	}
+}
+
+// Reopen 重新打开日志文件，外部logrotate移动文件后调用，打开失败时继续写入原文件
+func (w *RotateWriter) Reopen() error {
+	w.l.Lock()
+	defer w.l.Unlock()
+	return w.open()
+}
+
+// Close 关闭文件，等待压缩和清理完成
+func (w *RotateWriter) Close() error {
+	w.l.Lock()
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.l.Unlock()
+	w.wg.Wait()
+	return err
+}
@@ -0,0 +1,169 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hoorayui/core-framework/util"
+)
+
+func TestRotateWriter(t *testing.T) {
+	dir := t.TempDir()
+	// 过期的轮转文件被清理，名称相近的其他日志保留
+	expired := filepath.Join(dir, "app-20200101-000000.log.gz")
+	other := filepath.Join(dir, "app-error.log")
+	for _, f := range []string{expired, other} {
+		os.WriteFile(f, []byte("x"), 0644)
+		os.Chtimes(f, time.Now().AddDate(0, 0, -10), time.Now().AddDate(0, 0, -10))
+	}
+	w, err := NewRotateWriter(filepath.Join(dir, "app.log"), RotateConfig{MaxSize: 100, Compress: true, ReserveDays: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 10; n++ {
+		w.Write([]byte(strings.Repeat("a", 40) + "\n"))
+	}
+	w.Close()
+
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Fatal("过期的轮转文件应被删除")
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatal("不应删除其他日志文件")
+	}
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-2*.log.gz"))
+	if len(backups) != 4 {
+		t.Fatalf("期望4个压缩的轮转文件，实际%d", len(backups))
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "app.log")); info.Size() > 100 {
+		t.Fatalf("日志文件超过最大长度:%d", info.Size())
+	}
+}
+
+func TestRotateDaily(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	// 启动时日志文件最后写入的时间是前一天
+	yesterday := time.Now().AddDate(0, 0, -1)
+	os.WriteFile(path, []byte("yesterday\n"), 0644)
+	os.Chtimes(path, yesterday, yesterday)
+	w, err := NewRotateWriter(path, RotateConfig{Daily: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("today\n"))
+	w.Write([]byte("today\n"))
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-"+yesterday.In(util.Now().Location()).Format("20060102")+"-*.log"))
+	if len(backups) != 1 {
+		t.Fatalf("跨天后应轮转一次，实际%d个轮转文件", len(backups))
+	}
+	if b, _ := os.ReadFile(backups[0]); string(b) != "yesterday\n" {
+		t.Errorf("轮转文件内容:%q", b)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "today\ntoday\n" {
+		t.Errorf("当前文件内容:%q", b)
+	}
+
+	// 未启用按天轮转时不轮转
+	other := filepath.Join(dir, "other.log")
+	os.WriteFile(other, []byte("yesterday\n"), 0644)
+	os.Chtimes(other, yesterday, yesterday)
+	ow, err := NewRotateWriter(other, RotateConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ow.Close()
+	ow.Write([]byte("today\n"))
+	if backups, _ := filepath.Glob(filepath.Join(dir, "other-*.log")); len(backups) != 0 {
+		t.Errorf("未启用按天轮转:%v", backups)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := NewRotateWriter(path, RotateConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write([]byte("before\n"))
+	// 外部logrotate移动文件后通知重新打开
+	moved := path + ".1"
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("moved\n"))
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("after\n"))
+	if b, _ := os.ReadFile(moved); string(b) != "before\nmoved\n" {
+		t.Errorf("移动后的文件内容:%q", b)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "after\n" {
+		t.Errorf("重新打开的文件内容:%q", b)
+	}
+}
+
+func TestRotateOpenFailed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := NewRotateWriter(path, RotateConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write([]byte("before\n"))
+
+	errOpen := errors.New("打开失败")
+	openFile = func(string, int, os.FileMode) (*os.File, error) {
+		return nil, errOpen
+	}
+	defer func() { openFile = os.OpenFile }()
+	if err := w.Rotate(); !errors.Is(err, errOpen) {
+		t.Fatalf("打开新文件失败时应返回错误:%v", err)
+	}
+	if _, err := w.Write([]byte("after\n")); err != nil {
+		t.Fatalf("轮转失败后应继续写入原文件:%v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "before\nafter\n" {
+		t.Errorf("轮转失败后原文件应移回:%q", b)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "app-*")); len(files) != 0 {
+		t.Errorf("轮转失败时不应保留轮转文件:%v", files)
+	}
+}
+
+func TestReopenFailed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := NewRotateWriter(path, RotateConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	moved := path + ".1"
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatal(err)
+	}
+	// 日志文件路径被目录占用，打开失败
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reopen(); err == nil {
+		t.Fatal("打开失败时应返回错误")
+	}
+	if _, err := w.Write([]byte("after\n")); err != nil {
+		t.Fatalf("重新打开失败后应继续写入原文件:%v", err)
+	}
+	if b, _ := os.ReadFile(moved); string(b) != "after\n" {
+		t.Errorf("原文件内容:%q", b)
+	}
+}
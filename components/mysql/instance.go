@@ -41,11 +41,10 @@ func (i *Instance) Init(config interface{}) error {
 	} else {
 		logrus.Fatalf("不支持[%s]数据库", i.config.DBDriver)
 	}
-	rawDB, err := gorm.Open(dialector, &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		SkipDefaultTransaction: true,
 		Logger:                 mysqlLogger(),
 	})
-	rawDB.Exec("set time_zone=\"+08:00\";")
 	if err != nil {
 		// 数据库未创建
 		if strings.Contains(err.Error(), "Unknown database") {
@@ -55,7 +54,13 @@ func (i *Instance) Init(config interface{}) error {
 		logrus.Fatalf("Connect database failed, err: %v", err)
 		return err
 	}
-	sqlDB, err := rawDB.DB()
+	if i.config.DBDriver == "mysql" {
+		db.Exec("set time_zone=\"+08:00\";")
+	}
+	i.client = db
+	rawDB = db
+	debugMode = i.config.DebugMode
+	sqlDB, err := db.DB()
 	if nil != err {
 		logrus.Fatalf("获取数据库实例失败，%s", err.Error())
 	}
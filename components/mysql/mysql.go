@@ -1,13 +1,12 @@
 package mysql
 
 import (
-	"fmt"
 	"io"
 	"log"
 	"os"
 	"time"
 
-	"github.com/hoorayui/core-framework/util/flag"
+	applog "github.com/hoorayui/core-framework/components/log"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm/logger"
@@ -68,11 +67,10 @@ func (b *DB) Commit() {
 	}
 }
 func mysqlLogger() logger.Interface {
-	// TODO 替换为应用名称
-	path := fmt.Sprintf("%s/db.log", flag.LogDir)
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0777)
+	// 与应用日志使用相同的目录和轮转配置
+	f, err := applog.NewFileWriter("db.log")
 	if nil != err {
-		logrus.Fatalf("打开日志文件[%s]失败1", path)
+		logrus.Fatalf("打开数据库日志文件失败:%s", err.Error())
 		return nil
 	}
 	writer := io.MultiWriter(os.Stdout, f)
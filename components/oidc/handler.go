@@ -0,0 +1,263 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoorayui/core-framework/components/jwt"
+)
+
+// ErrLoginDenied 身份提供方拒绝登录或用户映射失败
+var ErrLoginDenied = errors.New("oidc登录被拒绝")
+
+// StateCookie 保存state摘要的cookie，回调时校验state是否由当前浏览器发起，防止登录CSRF
+const StateCookie = "oidc_state"
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AuthCodeURL 生成身份提供方的登录地址，state、nonce和PKCE的code_verifier保存到state存储。
+// 调用方需要将state与浏览器绑定，回调时校验，LoginHandler和CallbackHandler通过StateCookie绑定
+func (i *Instance) AuthCodeURL(ctx context.Context) (string, error) {
+	u, _, err := i.authCodeURL(ctx)
+	return u, err
+}
+
+func (i *Instance) authCodeURL(ctx context.Context) (string, string, error) {
+	if i.states == nil {
+		return "", "", errors.New("未设置oidc state存储")
+	}
+	m, err := i.provider.Metadata(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	ls := &LoginState{}
+	state, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	if ls.Nonce, err = randomString(); err != nil {
+		return "", "", err
+	}
+	if ls.CodeVerifier, err = randomString(); err != nil {
+		return "", "", err
+	}
+	if err := i.states.Save(state, ls, time.Duration(i.config.StateExpire)*time.Second); err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(ls.CodeVerifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {i.config.ClientID},
+		"redirect_uri":          {i.config.RedirectURL},
+		"scope":                 {strings.Join(i.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {ls.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return m.AuthorizationEndpoint + sep + query.Encode(), state, nil
+}
+
+// stateHash cookie中保存的state摘要
+func stateHash(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// setStateCookie 设置或清除state cookie，身份提供方回调是跨站跳转，SameSite使用Lax
+func (i *Instance) setStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(StateCookie, value, maxAge, "/", "", strings.HasPrefix(i.config.RedirectURL, "https://"), true)
+}
+
+// tokenResponse 身份提供方token端点的响应
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange 校验state，使用授权码换取id token并校验
+func (i *Instance) Exchange(ctx context.Context, code, state string) (*IDTokenClaims, error) {
+	if i.states == nil {
+		return nil, errors.New("未设置oidc state存储")
+	}
+	ls, err := i.states.Take(state)
+	if err != nil {
+		return nil, err
+	}
+	m, err := i.provider.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {i.config.RedirectURL},
+		"client_id":     {i.config.ClientID},
+		"code_verifier": {ls.CodeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(i.config.ClientID), url.QueryEscape(i.config.ClientSecret))
+	}
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w:%s", ErrProvider, err.Error())
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w:%s", ErrProvider, err.Error())
+	}
+	tr := &tokenResponse{}
+	if err := json.Unmarshal(body, tr); err != nil {
+		return nil, fmt.Errorf("%w:token响应%d %s", ErrProvider, resp.StatusCode, body)
+	}
+	if tr.Error != "" {
+		return nil, fmt.Errorf("%w:%s %s", ErrLoginDenied, tr.Error, tr.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || tr.IDToken == "" {
+		return nil, fmt.Errorf("%w:token响应%d中没有id_token", ErrProvider, resp.StatusCode)
+	}
+	return i.VerifyIDToken(ctx, tr.IDToken, ls.Nonce)
+}
+
+// Login 映射本地用户并签发本地token，jwt未配置刷新token时只返回访问token
+func (i *Instance) Login(ctx context.Context, claims *IDTokenClaims, opts ...jwt.TokenOption) (*jwt.TokenPair, error) {
+	if i.mapper == nil {
+		return nil, ErrNoUserMapper
+	}
+	userID, username, err := i.mapper(ctx, claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w:%s", ErrLoginDenied, err.Error())
+	}
+	issuer := i.issuer
+	if issuer == nil {
+		issuer = jwt.GetInstance()
+	}
+	if issuer == nil {
+		return nil, jwt.ErrNotInitialized
+	}
+	pair, err := issuer.IssuePair(userID, username, opts...)
+	if !errors.Is(err, jwt.ErrRefreshDisabled) {
+		return pair, err
+	}
+	token, err := issuer.GenToken(userID, username, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &jwt.TokenPair{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(issuer.Expire() / time.Second),
+	}, nil
+}
+
+// statusCode 登录错误对应的http状态码
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrStateInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrIDTokenInvalid):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrLoginDenied):
+		return http.StatusForbidden
+	case errors.Is(err, ErrProvider):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// LoginHandler 跳转到身份提供方登录
+func (i *Instance) LoginHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, state, err := i.authCodeURL(c.Request.Context())
+		if err != nil {
+			c.JSON(statusCode(err), gin.H{"error": err.Error()})
+			return
+		}
+		i.setStateCookie(c, stateHash(state), i.config.StateExpire)
+		c.Redirect(http.StatusFound, u)
+	}
+}
+
+// CallbackHandler 身份提供方回调，state与登录时的cookie一致时才换取token，登录成功时返回本地token
+func (i *Instance) CallbackHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cookie, _ := c.Cookie(StateCookie)
+		i.setStateCookie(c, "", -1)
+		state := c.Query("state")
+		if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(stateHash(state))) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s:与发起登录的浏览器不一致", ErrStateInvalid)})
+			return
+		}
+		if e := c.Query("error"); e != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("%s:%s %s", ErrLoginDenied, e, c.Query("error_description"))})
+			return
+		}
+		claims, err := i.Exchange(c.Request.Context(), c.Query("code"), state)
+		if err != nil {
+			c.JSON(statusCode(err), gin.H{"error": err.Error()})
+			return
+		}
+		pair, err := i.Login(c.Request.Context(), claims, jwt.WithClient(c.Request.UserAgent(), c.ClientIP()))
+		if err != nil {
+			c.JSON(statusCode(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, pair)
+	}
+}
+
+// LoginHandler 使用全局实例跳转到身份提供方登录
+func LoginHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if instance == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "oidc组件未初始化"})
+			return
+		}
+		instance.LoginHandler()(c)
+	}
+}
+
+// CallbackHandler 使用全局实例处理身份提供方回调
+func CallbackHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if instance == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "oidc组件未初始化"})
+			return
+		}
+		instance.CallbackHandler()(c)
+	}
+}
@@ -0,0 +1,128 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hoorayui/core-framework/components/jwt"
+	"github.com/hoorayui/core-framework/components/redis"
+	"github.com/hoorayui/core-framework/types"
+)
+
+// ErrNoUserMapper 未设置用户映射
+var ErrNoUserMapper = errors.New("未设置oidc用户映射")
+
+// UserMapper 将身份提供方的用户映射为本地用户，返回错误时拒绝登录
+type UserMapper func(ctx context.Context, claims *IDTokenClaims) (userID int64, username string, err error)
+
+type Instance struct {
+	config   types.OIDCConfig
+	client   *http.Client
+	provider *provider
+	states   StateStore
+	mapper   UserMapper
+	// 签发本地token的实例，为nil时使用全局实例
+	issuer *jwt.Instance
+}
+
+var instance *Instance
+
+// New 根据配置创建实例，不替换全局实例，需要通过SetStateStore设置state存储
+func New(config types.OIDCConfig) (*Instance, error) {
+	i := &Instance{config: config}
+	if err := i.Validate(); err != nil {
+		return nil, err
+	}
+	i.init()
+	return i, nil
+}
+
+// GetName 组件名称
+func (i *Instance) GetName() string {
+	return "oidc"
+}
+
+// Init 初始化实例，state保存在redis中，需要先加载redis组件
+func (i *Instance) Init(config interface{}) error {
+	bytes, _ := json.Marshal(config)
+	json.Unmarshal(bytes, &i.config)
+	if err := i.Validate(); err != nil {
+		return err
+	}
+	if redis.GetInstance() == nil || redis.GetInstance().Client() == nil {
+		return errors.New("oidc需要先加载redis组件")
+	}
+	i.init()
+	i.states = NewRedisStateStore(redis.GetInstance().Client(), i.config.KeyPrefix)
+	instance = i
+	return nil
+}
+
+func (i *Instance) init() {
+	if i.client == nil {
+		i.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	i.provider = newProvider(i.config.Issuer, i.client)
+}
+
+// Validate 验证配置
+func (i *Instance) Validate() error {
+	if i.config.Issuer == "" {
+		return errors.New("oidc身份提供方地址未配置")
+	}
+	i.config.Issuer = strings.TrimSuffix(i.config.Issuer, "/")
+	if i.config.ClientID == "" {
+		return errors.New("oidc client_id未配置")
+	}
+	if i.config.RedirectURL == "" {
+		return errors.New("oidc回调地址未配置")
+	}
+	if len(i.config.Scopes) == 0 {
+		i.config.Scopes = []string{"openid", "profile", "email"}
+	}
+	hasOpenID := false
+	for _, scope := range i.config.Scopes {
+		hasOpenID = hasOpenID || scope == "openid"
+	}
+	if !hasOpenID {
+		i.config.Scopes = append([]string{"openid"}, i.config.Scopes...)
+	}
+	if i.config.StateExpire <= 0 {
+		i.config.StateExpire = 600
+	}
+	if i.config.Leeway < 0 {
+		return errors.New("oidc时间误差不能小于0")
+	}
+	if i.config.KeyPrefix == "" {
+		i.config.KeyPrefix = "oidc:"
+	}
+	return nil
+}
+
+// SetUserMapper 设置用户映射
+func (i *Instance) SetUserMapper(mapper UserMapper) {
+	i.mapper = mapper
+}
+
+// SetStateStore 设置state存储
+func (i *Instance) SetStateStore(store StateStore) {
+	i.states = store
+}
+
+// SetIssuer 设置签发本地token的jwt实例
+func (i *Instance) SetIssuer(issuer *jwt.Instance) {
+	i.issuer = issuer
+}
+
+// GetInstance 获取实例
+func GetInstance() *Instance {
+	return instance
+}
+
+// Close 关闭
+func (i *Instance) Close() {
+}
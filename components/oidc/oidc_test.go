@@ -0,0 +1,249 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt"
+	"github.com/hoorayui/core-framework/components/jwt"
+	"github.com/hoorayui/core-framework/types"
+)
+
+// mockProvider 测试用的身份提供方
+type mockProvider struct {
+	*httptest.Server
+	key   *jwt.Key
+	l     sync.Mutex
+	codes map[string]url.Values // 授权码对应的登录参数
+	// 获取公钥的次数
+	jwksHits int
+}
+
+// claims 有效的id token声明
+func (p *mockProvider) claims(nonce string) gojwt.MapClaims {
+	return gojwt.MapClaims{
+		"iss":   p.URL,
+		"sub":   "u-1",
+		"aud":   []string{"app"},
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": nonce,
+		"email": "zhangsan@example.com",
+	}
+}
+
+// sign 使用key签名id token，header中的kid为kid
+func (p *mockProvider) sign(claims gojwt.MapClaims, key *jwt.Key, kid string) string {
+	token := gojwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = kid
+	str, _ := token.SignedString(key.Private)
+	return str
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := jwt.NewKey(jwt.AlgRS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &mockProvider{key: key, codes: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc(DiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Metadata{
+			Issuer:                p.URL,
+			AuthorizationEndpoint: p.URL + "/authorize",
+			TokenEndpoint:         p.URL + "/token",
+			JWKSURI:               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.l.Lock()
+		defer p.l.Unlock()
+		p.jwksHits++
+		json.NewEncoder(w).Encode(jwt.JWKS{Keys: []jwt.JWK{p.key.JWK()}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		code := "code-" + query.Get("state")[:8]
+		p.l.Lock()
+		p.codes[code] = query
+		p.l.Unlock()
+		http.Redirect(w, r, query.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {query.Get("state")}}.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.l.Lock()
+		login, ok := p.codes[r.Form.Get("code")]
+		delete(p.codes, r.Form.Get("code"))
+		p.l.Unlock()
+		challenge := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		id, secret, _ := r.BasicAuth()
+		if !ok || id != "app" || secret != "app-secret" ||
+			base64.RawURLEncoding.EncodeToString(challenge[:]) != login.Get("code_challenge") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		idToken := p.sign(p.claims(login.Get("nonce")), p.key, p.key.ID)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": idToken})
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// newTestInstance 使用测试身份提供方创建实例
+func newTestInstance(t *testing.T, provider *mockProvider) *Instance {
+	t.Helper()
+	i, err := New(types.OIDCConfig{
+		Issuer:       provider.URL,
+		ClientID:     "app",
+		ClientSecret: "app-secret",
+		RedirectURL:  "http://localhost/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.SetStateStore(NewMemoryStateStore())
+	return i
+}
+
+func TestLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	provider := newMockProvider(t)
+	defer provider.Close()
+
+	issuer, err := jwt.New(types.JWTConfig{Secret: "test-secret-0123456789abcdefghijklmnop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := newTestInstance(t, provider)
+	i.SetIssuer(issuer)
+	i.SetUserMapper(func(ctx context.Context, claims *IDTokenClaims) (int64, string, error) {
+		return 1, claims.Raw["email"].(string), nil
+	})
+	r := gin.New()
+	r.GET("/login", i.LoginHandler())
+	r.GET("/callback", i.CallbackHandler())
+
+	// 登录跳转到身份提供方，身份提供方回调时带上授权码
+	login := func() (*url.URL, *http.Cookie) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+		var cookie *http.Cookie
+		for _, c := range w.Result().Cookies() {
+			if c.Name == StateCookie {
+				cookie = c
+			}
+		}
+		if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+			t.Fatalf("登录时应设置HttpOnly、SameSite的state cookie:%v", cookie)
+		}
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		resp, err := client.Get(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		callback, _ := url.Parse(resp.Header.Get("Location"))
+		return callback, cookie
+	}
+	callback := func(u *url.URL, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/callback?"+u.RawQuery, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	u, cookie := login()
+	_, other := login()
+	// 没有cookie或cookie来自其他登录时拒绝，防止攻击者将自己的授权码注入用户浏览器
+	for _, c := range []*http.Cookie{nil, other} {
+		if w := callback(u, c); w.Code != http.StatusBadRequest {
+			t.Fatalf("state与cookie不一致时应拒绝，实际%d", w.Code)
+		}
+	}
+
+	w := callback(u, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("登录失败:%d %s", w.Code, w.Body.String())
+	}
+	pair := &jwt.TokenPair{}
+	json.Unmarshal(w.Body.Bytes(), pair)
+	claims, err := issuer.ParseToken(pair.AccessToken)
+	if err != nil || claims.Username != "zhangsan@example.com" {
+		t.Fatalf("本地token不正确:%v", err)
+	}
+
+	// state只能使用一次
+	if w := callback(u, cookie); w.Code != http.StatusBadRequest {
+		t.Fatalf("重复回调应失败，实际%d", w.Code)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	provider := newMockProvider(t)
+	defer provider.Close()
+	i := newTestInstance(t, provider)
+	ctx := context.Background()
+	if _, err := i.VerifyIDToken(ctx, provider.sign(provider.claims("n-1"), provider.key, provider.key.ID), "n-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := jwt.NewKey(jwt.AlgES256)
+	cases := map[string]struct {
+		modify func(gojwt.MapClaims)
+		key    *jwt.Key
+		kid    string
+	}{
+		"nonce不正确": {modify: func(c gojwt.MapClaims) { c["nonce"] = "n-2" }},
+		"接收方不正确":   {modify: func(c gojwt.MapClaims) { c["aud"] = "other" }},
+		"azp不正确":   {modify: func(c gojwt.MapClaims) { c["azp"] = "other" }},
+		"已过期":      {modify: func(c gojwt.MapClaims) { c["exp"] = time.Now().Add(-10 * time.Minute).Unix() }},
+		"签发人不正确":   {modify: func(c gojwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		"公钥与算法不一致": {key: other, kid: provider.key.ID},
+		"使用其他公钥签名": {key: other, kid: other.ID},
+	}
+	for name, c := range cases {
+		claims := provider.claims("n-1")
+		if c.modify != nil {
+			c.modify(claims)
+		}
+		key, kid := provider.key, provider.key.ID
+		if c.key != nil {
+			key, kid = c.key, c.kid
+		}
+		if _, err := i.VerifyIDToken(ctx, provider.sign(claims, key, kid), "n-1"); !errors.Is(err, ErrIDTokenInvalid) {
+			t.Errorf("%s:应返回ErrIDTokenInvalid，实际%v", name, err)
+		}
+	}
+
+	// 未知的kid重新获取公钥，仍不存在时返回错误
+	i.provider.l.Lock()
+	i.provider.fetchedAt = time.Now().Add(-time.Minute)
+	i.provider.l.Unlock()
+	provider.l.Lock()
+	hits := provider.jwksHits
+	provider.l.Unlock()
+	if _, err := i.VerifyIDToken(ctx, provider.sign(provider.claims("n-1"), provider.key, "unknown"), "n-1"); !errors.Is(err, ErrIDTokenInvalid) {
+		t.Errorf("未知的kid:应返回ErrIDTokenInvalid，实际%v", err)
+	}
+	provider.l.Lock()
+	defer provider.l.Unlock()
+	if provider.jwksHits != hits+1 {
+		t.Errorf("未知的kid应重新获取公钥，获取%d次", provider.jwksHits-hits)
+	}
+}
@@ -0,0 +1,241 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt"
+	"github.com/hoorayui/core-framework/components/jwt"
+	"github.com/hoorayui/core-framework/util"
+)
+
+// DiscoveryPath 身份提供方配置的路径
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+// jwksRefreshInterval 遇到未知kid时重新获取公钥的最小间隔
+const jwksRefreshInterval = 10 * time.Second
+
+// id token校验错误
+var (
+	ErrProvider       = errors.New("oidc身份提供方请求失败")
+	ErrIDTokenInvalid = errors.New("oidc id token无效")
+)
+
+// Metadata 身份提供方的配置，格式见OpenID Connect Discovery 1.0
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// provider 缓存身份提供方的配置和公钥
+type provider struct {
+	issuer string
+	client *http.Client
+
+	l         sync.Mutex
+	metadata  *Metadata
+	keys      map[string]*jwt.Key
+	fetchedAt time.Time
+}
+
+func newProvider(issuer string, client *http.Client) *provider {
+	return &provider{issuer: issuer, client: client}
+}
+
+// getJSON 请求身份提供方并解析json响应
+func (p *provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w:%s", ErrProvider, err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%w:%s返回%d %s", ErrProvider, url, resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Metadata 获取身份提供方的配置，成功后缓存
+func (p *provider) Metadata(ctx context.Context) (*Metadata, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	m := &Metadata{}
+	if err := p.getJSON(ctx, p.issuer+DiscoveryPath, m); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(m.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("%w:配置的issuer[%s]与身份提供方的issuer[%s]不一致", ErrProvider, p.issuer, m.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, fmt.Errorf("%w:身份提供方配置缺少端点", ErrProvider)
+	}
+	p.metadata = m
+	return m, nil
+}
+
+// key 按kid查找公钥，未找到时重新获取公钥，kid为空且只有一个公钥时使用该公钥
+func (p *provider) key(ctx context.Context, kid string) (*jwt.Key, error) {
+	m, err := p.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.l.Lock()
+	defer p.l.Unlock()
+	if k := p.lookup(kid); k != nil {
+		return k, nil
+	}
+	if util.Now().Sub(p.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("%w:公钥[%s]不存在", ErrIDTokenInvalid, kid)
+	}
+	jwks := jwt.JWKS{}
+	if err := p.getJSON(ctx, m.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	p.fetchedAt = util.Now()
+	p.keys = map[string]*jwt.Key{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// 不支持的公钥跳过，使用这些公钥签名的token校验失败
+		if k, err := jwk.Key(); err == nil {
+			p.keys[k.ID] = k
+		}
+	}
+	if k := p.lookup(kid); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w:公钥[%s]不存在", ErrIDTokenInvalid, kid)
+}
+
+func (p *provider) lookup(kid string) *jwt.Key {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k
+		}
+	}
+	return p.keys[kid]
+}
+
+// Audience id token的接收方，可以是字符串或数组
+type Audience []string
+
+// UnmarshalJSON 兼容字符串和数组
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Contains 是否包含接收方
+func (a Audience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// IDTokenClaims id token中的声明
+type IDTokenClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          Audience `json:"aud"`
+	ExpiresAt         int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	AuthorizedParty   string   `json:"azp"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	// 所有声明，用于映射自定义声明
+	Raw map[string]interface{} `json:"-"`
+}
+
+// Valid 声明由VerifyIDToken校验
+func (c *IDTokenClaims) Valid() error {
+	return nil
+}
+
+// VerifyIDToken 校验id token的签名、签发人、接收方、有效期和nonce
+func (i *Instance) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDTokenClaims, error) {
+	m, err := i.provider.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := &IDTokenClaims{}
+	parser := &gojwt.Parser{
+		ValidMethods:         []string{jwt.AlgRS256, jwt.AlgES256, jwt.AlgEdDSA},
+		SkipClaimsValidation: true,
+	}
+	_, err = parser.ParseWithClaims(rawIDToken, claims, func(token *gojwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		k, err := i.provider.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != k.Method.Alg() {
+			return nil, fmt.Errorf("公钥[%s]只支持[%s]，实际为[%s]", kid, k.Method.Alg(), token.Method.Alg())
+		}
+		return k.Public, nil
+	})
+	if err != nil {
+		var ve *gojwt.ValidationError
+		if errors.As(err, &ve) && errors.Is(ve.Inner, ErrProvider) {
+			return nil, ve.Inner
+		}
+		return nil, fmt.Errorf("%w:%s", ErrIDTokenInvalid, err.Error())
+	}
+	now := util.Now().Unix()
+	leeway := int64(i.config.Leeway)
+	switch {
+	case claims.Issuer != m.Issuer:
+		return nil, fmt.Errorf("%w:签发人[%s]不正确", ErrIDTokenInvalid, claims.Issuer)
+	case !claims.Audience.Contains(i.config.ClientID):
+		return nil, fmt.Errorf("%w:接收方不包含[%s]", ErrIDTokenInvalid, i.config.ClientID)
+	case claims.AuthorizedParty != "" && claims.AuthorizedParty != i.config.ClientID:
+		return nil, fmt.Errorf("%w:azp[%s]不正确", ErrIDTokenInvalid, claims.AuthorizedParty)
+	case claims.ExpiresAt == 0 || now > claims.ExpiresAt+leeway:
+		return nil, fmt.Errorf("%w:已过期", ErrIDTokenInvalid)
+	case claims.IssuedAt > now+leeway:
+		return nil, fmt.Errorf("%w:签发时间晚于当前时间", ErrIDTokenInvalid)
+	case claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w:nonce不正确", ErrIDTokenInvalid)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w:缺少sub", ErrIDTokenInvalid)
+	}
+	if parts := strings.Split(rawIDToken, "."); len(parts) == 3 {
+		if payload, err := gojwt.DecodeSegment(parts[1]); err == nil {
+			json.Unmarshal(payload, &claims.Raw)
+		}
+	}
+	return claims, nil
+}
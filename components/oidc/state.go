@@ -0,0 +1,111 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+	"github.com/hoorayui/core-framework/util"
+)
+
+// ErrStateInvalid state不存在、已使用或已过期
+var ErrStateInvalid = errors.New("oidc登录state无效或已过期")
+
+// LoginState 登录开始时保存，回调时按state取出
+type LoginState struct {
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// StateStore 登录state存储，state只能取出一次
+type StateStore interface {
+	Save(state string, s *LoginState, ttl time.Duration) error
+	Take(state string) (*LoginState, error) // 取出并删除，不存在时返回ErrStateInvalid
+}
+
+// RedisStateStore redis state存储
+type RedisStateStore struct {
+	client *goredis.Client
+	prefix string
+}
+
+// NewRedisStateStore 创建redis state存储
+func NewRedisStateStore(client *goredis.Client, prefix string) *RedisStateStore {
+	return &RedisStateStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStateStore) key(state string) string {
+	return s.prefix + "state:" + state
+}
+
+// Save 保存state
+func (s *RedisStateStore) Save(state string, ls *LoginState, ttl time.Duration) error {
+	b, err := json.Marshal(ls)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(s.key(state), string(b), ttl).Err()
+}
+
+// Take 取出并删除state
+func (s *RedisStateStore) Take(state string) (*LoginState, error) {
+	pipe := s.client.TxPipeline()
+	get := pipe.Get(s.key(state))
+	pipe.Del(s.key(state))
+	if _, err := pipe.Exec(); err != nil && err != goredis.Nil {
+		return nil, err
+	}
+	raw, err := get.Result()
+	if err == goredis.Nil {
+		return nil, ErrStateInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	ls := &LoginState{}
+	return ls, json.Unmarshal([]byte(raw), ls)
+}
+
+// MemoryStateStore 进程内的state存储，只用于单实例部署和测试
+type MemoryStateStore struct {
+	l      sync.Mutex
+	states map[string]memoryState
+}
+
+type memoryState struct {
+	state    *LoginState
+	expireAt time.Time
+}
+
+// NewMemoryStateStore 创建进程内的state存储
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: map[string]memoryState{}}
+}
+
+// Save 保存state，同时清理过期的state
+func (s *MemoryStateStore) Save(state string, ls *LoginState, ttl time.Duration) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	now := util.Now()
+	for k, v := range s.states {
+		if now.After(v.expireAt) {
+			delete(s.states, k)
+		}
+	}
+	s.states[state] = memoryState{state: ls, expireAt: now.Add(ttl)}
+	return nil
+}
+
+// Take 取出并删除state
+func (s *MemoryStateStore) Take(state string) (*LoginState, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	v, ok := s.states[state]
+	delete(s.states, state)
+	if !ok || util.Now().After(v.expireAt) {
+		return nil, ErrStateInvalid
+	}
+	return v.state, nil
+}
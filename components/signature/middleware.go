@@ -0,0 +1,142 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoorayui/core-framework/components/config"
+	"github.com/hoorayui/core-framework/util"
+)
+
+// AccessKeyKey 校验通过的access key在gin上下文中的key
+const AccessKeyKey = "signature_access_key"
+
+// DefaultSkew 默认允许的时间误差
+const DefaultSkew = 5 * time.Minute
+
+// Config 签名校验中间件配置
+type Config struct {
+	// 查询access key对应的密钥，为nil时使用配置的access_keys
+	Credentials func(accessKey string) (string, bool)
+	// 请求时间与服务器时间允许的误差，默认5分钟
+	Skew time.Duration
+	// nonce存储，默认为进程内存储
+	Nonces NonceStore
+	// 请求体最大长度，默认10MB
+	MaxBodySize int64
+}
+
+// optionCredentials 配置的access_keys，配置读取时key被转换为小写
+func optionCredentials(accessKey string) (string, bool) {
+	options := config.GetOptions()
+	if options == nil {
+		return "", false
+	}
+	secret, ok := options.AccessKeys[strings.ToLower(accessKey)]
+	return secret, ok && secret != ""
+}
+
+// Error 校验失败的响应
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Middleware 校验请求签名，nonce在时间误差的两倍时间内不能重复使用
+func Middleware(cfg Config) gin.HandlerFunc {
+	if cfg.Credentials == nil {
+		cfg.Credentials = optionCredentials
+	}
+	if cfg.Skew <= 0 {
+		cfg.Skew = DefaultSkew
+	}
+	if cfg.Nonces == nil {
+		cfg.Nonces = NewMemoryNonceStore()
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 10 << 20
+	}
+	return func(c *gin.Context) {
+		accessKey, err := cfg.verify(c.Request)
+		if err != nil {
+			c.AbortWithStatusJSON(statusCode(err), Error{Error: err.Error()})
+			return
+		}
+		c.Set(AccessKeyKey, accessKey)
+		c.Next()
+	}
+}
+
+// statusCode 校验错误对应的http状态码，nonce存储等内部错误返回500
+func statusCode(err error) int {
+	for _, target := range []error{ErrMissingHeader, ErrUnknownAccessKey, ErrTimestampSkew, ErrNonceReused, ErrSignatureMismatch} {
+		if errors.Is(err, target) {
+			return http.StatusUnauthorized
+		}
+	}
+	if errors.Is(err, ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+// verify 校验签名，通过后返回access key
+func (cfg Config) verify(req *http.Request) (string, error) {
+	accessKey := req.Header.Get(HeaderAccessKey)
+	timestamp := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	signature := req.Header.Get(HeaderSignature)
+	if accessKey == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", ErrMissingHeader
+	}
+	secret, ok := cfg.Credentials(accessKey)
+	if !ok {
+		return "", fmt.Errorf("%w[%s]", ErrUnknownAccessKey, accessKey)
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w:时间戳[%s]不正确", ErrTimestampSkew, timestamp)
+	}
+	if skew := util.Now().Sub(time.Unix(ts, 0)); skew > cfg.Skew || skew < -cfg.Skew {
+		return "", ErrTimestampSkew
+	}
+	var body []byte
+	if req.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(req.Body, cfg.MaxBodySize+1))
+		if err != nil {
+			return "", err
+		}
+		if int64(len(body)) > cfg.MaxBodySize {
+			return "", ErrBodyTooLarge
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	expected := Sign(secret, CanonicalString(req, body, accessKey, timestamp, nonce))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return "", ErrSignatureMismatch
+	}
+	// 签名正确后才记录nonce，避免伪造的请求占用nonce。access key不区分大小写，
+	// 按小写记录，避免修改大小写后重放
+	ok, err = cfg.Nonces.Add(strings.ToLower(accessKey)+":"+nonce, 2*cfg.Skew)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrNonceReused
+	}
+	return accessKey, nil
+}
+
+// AccessKey 校验通过的调用方access key，c可以是*gin.Context
+func AccessKey(c context.Context) string {
+	accessKey, _ := c.Value(AccessKeyKey).(string)
+	return accessKey
+}
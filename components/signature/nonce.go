@@ -0,0 +1,63 @@
+package signature
+
+import (
+	"sync"
+	"time"
+
+	goredis "github.com/go-redis/redis"
+	"github.com/hoorayui/core-framework/util"
+)
+
+// NonceStore 记录已使用的nonce
+type NonceStore interface {
+	// Add 记录nonce，ttl内已记录过时返回false
+	Add(nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore 进程内的nonce存储，多实例部署时使用RedisNonceStore
+type MemoryNonceStore struct {
+	l       sync.Mutex
+	nonces  map[string]time.Time // nonce的过期时间
+	cleaned time.Time
+}
+
+// NewMemoryNonceStore 创建进程内的nonce存储
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: map[string]time.Time{}}
+}
+
+// Add 记录nonce，每隔ttl清理一次过期的nonce
+func (s *MemoryNonceStore) Add(nonce string, ttl time.Duration) (bool, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	now := util.Now()
+	if now.Sub(s.cleaned) > ttl {
+		for k, expire := range s.nonces {
+			if now.After(expire) {
+				delete(s.nonces, k)
+			}
+		}
+		s.cleaned = now
+	}
+	if expire, ok := s.nonces[nonce]; ok && now.Before(expire) {
+		return false, nil
+	}
+	s.nonces[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// RedisNonceStore redis nonce存储
+type RedisNonceStore struct {
+	client *goredis.Client
+	prefix string
+}
+
+// NewRedisNonceStore 创建redis nonce存储
+func NewRedisNonceStore(client *goredis.Client, prefix string) *RedisNonceStore {
+	return &RedisNonceStore{client: client, prefix: prefix}
+}
+
+// Add 记录nonce
+func (s *RedisNonceStore) Add(nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(s.prefix+"nonce:"+nonce, 1, ttl).Result()
+}
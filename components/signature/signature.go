@@ -0,0 +1,79 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// 签名相关的请求头
+const (
+	HeaderAccessKey = "X-Access-Key"
+	HeaderTimestamp = "X-Timestamp" // unix时间，秒
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature" // 十六进制的HMAC-SHA256
+)
+
+// 签名校验错误
+var (
+	ErrMissingHeader     = errors.New("缺少签名请求头")
+	ErrUnknownAccessKey  = errors.New("access key不存在")
+	ErrTimestampSkew     = errors.New("请求时间超出允许范围")
+	ErrNonceReused       = errors.New("nonce重复使用")
+	ErrSignatureMismatch = errors.New("签名不正确")
+	ErrBodyTooLarge      = errors.New("请求体超过最大长度")
+)
+
+// hashBody 请求体的sha256摘要
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalQuery 按参数名和值排序的查询参数
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(query))
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// CanonicalString 待签名的字符串，每行依次为方法、路径、排序后的查询参数、请求体摘要、
+// 小写的access key、时间戳和nonce
+func CanonicalString(req *http.Request, body []byte, accessKey, timestamp, nonce string) string {
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.Join([]string{
+		strings.ToUpper(req.Method),
+		path,
+		canonicalQuery(req.URL.Query()),
+		hashBody(body),
+		strings.ToLower(accessKey),
+		timestamp,
+		nonce,
+	}, "\n")
+}
+
+// Sign 使用密钥计算签名
+func Sign(secret, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,78 @@
+package signature
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(Config{
+		Credentials: func(accessKey string) (string, bool) {
+			return "partner-secret", strings.EqualFold(accessKey, "partner")
+		},
+	}))
+	r.POST("/orders", func(c *gin.Context) {
+		c.String(http.StatusOK, AccessKey(c))
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	client := &http.Client{Transport: NewSigner("partner", "partner-secret").Transport(nil)}
+	resp, err := client.Post(server.URL+"/orders?b=2&a=1&a=0", "application/json", strings.NewReader(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("签名请求应通过，实际%d", resp.StatusCode)
+	}
+
+	signed := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		NewSigner("partner", "partner-secret").Sign(req)
+		return req
+	}
+	do := func(req *http.Request) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	req := signed("a")
+	if do(req) != http.StatusOK {
+		t.Fatal("首次请求应通过")
+	}
+	replay, _ := http.NewRequest(http.MethodPost, "/orders", strings.NewReader("a"))
+	replay.Header = req.Header.Clone()
+	if code := do(replay); code != http.StatusUnauthorized {
+		t.Fatalf("重放请求应拒绝，实际%d", code)
+	}
+	replay, _ = http.NewRequest(http.MethodPost, "/orders", strings.NewReader("a"))
+	replay.Header = req.Header.Clone()
+	replay.Header.Set(HeaderAccessKey, "PARTNER")
+	if code := do(replay); code != http.StatusUnauthorized {
+		t.Fatalf("修改access key大小写后重放应拒绝，实际%d", code)
+	}
+
+	req = signed("a")
+	req.Body = http.NoBody
+	if code := do(req); code != http.StatusUnauthorized {
+		t.Fatalf("修改请求体应拒绝，实际%d", code)
+	}
+
+	req = signed("a")
+	ts := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign("partner-secret", CanonicalString(req, []byte("a"), "partner", ts, req.Header.Get(HeaderNonce))))
+	if code := do(req); code != http.StatusUnauthorized {
+		t.Fatalf("过期请求应拒绝，实际%d", code)
+	}
+}
@@ -0,0 +1,80 @@
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/hoorayui/core-framework/components/config"
+	"github.com/hoorayui/core-framework/util"
+)
+
+// Signer 调用其他服务时签名请求
+type Signer struct {
+	AccessKey string
+	Secret    string
+}
+
+// NewSigner 创建签名器
+func NewSigner(accessKey, secret string) *Signer {
+	return &Signer{AccessKey: accessKey, Secret: secret}
+}
+
+// DefaultSigner 使用配置的access_key和access_secret
+func DefaultSigner() (*Signer, error) {
+	options := config.GetOptions()
+	if options == nil || options.AccessKey == "" || options.AccessSecret == "" {
+		return nil, errors.New("未配置access_key和access_secret")
+	}
+	return NewSigner(options.AccessKey, options.AccessSecret), nil
+}
+
+// Sign 读取请求体计算签名并设置签名请求头，请求体会被替换为可重复读取的副本
+func (s *Signer) Sign(req *http.Request) error {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+		body = b
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	timestamp := strconv.FormatInt(util.Now().Unix(), 10)
+	nonce := util.NewUUIDString("")
+	req.Header.Set(HeaderAccessKey, s.AccessKey)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, Sign(s.Secret, CanonicalString(req, body, s.AccessKey, timestamp, nonce)))
+	return nil
+}
+
+// Transport 签名所有请求的http.RoundTripper，base为nil时使用http.DefaultTransport
+//
+//	client := &http.Client{Transport: signer.Transport(nil)}
+func (s *Signer) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{signer: s, base: base}
+}
+
+type transport struct {
+	signer *Signer
+	base   http.RoundTripper
+}
+
+// RoundTrip 签名请求的副本，重试时重新生成nonce
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	if err := t.signer.Sign(clone); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(clone)
+}
@@ -31,8 +31,11 @@ func (c *core) InitComponents(components ...InterfaceComponents) {
 	}
 	logrus.Info("组件加载完成")
 }
-func (c *core) LoadComponents(component InterfaceComponents, config interface{}) {
-	component.Init(config)
+
+// LoadComponents 加载组件，初始化失败时仍然注册组件并返回错误，由调用方决定是否继续运行
+func (c *core) LoadComponents(component InterfaceComponents, config interface{}) error {
+	err := component.Init(config)
 	c.components[component.GetName()] = component
 	c.deferFuncs[component.GetName()] = component.Close
+	return err
 }
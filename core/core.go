@@ -53,18 +53,24 @@ func New(configFile string, components ...InterfaceComponents) *core {
 	if app.configFile == "" {
 		app.configFile = configFile
 	}
-	app.LoadComponents(&config.Instance{}, types.CfgConfig{
+	// 配置读取失败时其他组件都无法按配置初始化，日志配置错误时不能按配置输出日志，都直接退出
+	err := app.LoadComponents(&config.Instance{}, types.CfgConfig{
 		Path: app.configFile,
 	})
-	app.LoadComponents(&log.Instance{}, config.GetConfig("log"))
+	if err != nil {
+		logrus.Fatalf("组件[config]初始化失败:%s", err.Error())
+	}
+	if err := app.LoadComponents(&log.Instance{}, config.GetConfig("log")); err != nil {
+		logrus.Fatalf("组件[log]初始化失败:%s", err.Error())
+	}
 	app.InitComponents(components...)
 	return app
 }
 func Default(configFile string) *core {
+	// event依赖redis，需在redis之后加载
 	app := New(configFile, &mysql.Instance{},
-		&event.Instance{},
-		&mysql.Instance{},
-		&redis.Instance{})
+		&redis.Instance{},
+		&event.Instance{})
 	return app
 }
 
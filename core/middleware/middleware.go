@@ -43,6 +43,8 @@ func LogMiddleware(c *gin.Context) {
 	// request_id
 	uuid := util2.NewUUIDString("")
 	c.Params = append(c.Params, gin.Param{Key: "request_id", Value: uuid})
+	// 通过c.Value("request_id")传递给事件等组件
+	c.Set("request_id", uuid)
 	logger := logrus.WithField("request_id", uuid)
 
 	// 响应起始时间
@@ -30,6 +30,29 @@ var (
 		Name: "myapp_response_time_milliseconds",
 		Help: "api response time(ms)",
 	})
+	// 没有订阅者的事件数量
+	EventNoSubscriberCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "myapp_event_no_subscriber_total",
+		Help: "published events without subscriber",
+	}, []string{"topic"})
+	// 事件发布数量
+	EventPublishCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "myapp_event_publish_total",
+		Help: "published events by topic and outcome",
+	}, []string{"topic", "outcome"})
+	EventPublishHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "myapp_event_publish_seconds",
+		Help: "event publish latency(s)",
+	}, []string{"topic", "outcome"})
+	// 事件消费数量
+	EventConsumeCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "myapp_event_consume_total",
+		Help: "consumed events by topic and outcome",
+	}, []string{"topic", "outcome"})
+	EventConsumeHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "myapp_event_consume_seconds",
+		Help: "event handler latency(s)",
+	}, []string{"topic", "outcome"})
 	// promauto.NewCounterFunc(prometheus.CounterOpts{
 	// 	Name: "myapp_processed_ops_total",
 	// 	Help: "The total number of processed events",
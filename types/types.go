@@ -13,9 +13,25 @@ type Config struct {
 	// db flags
 	DB    DBConfig    `yaml:"mysql" json:"mysql" toml:"mysql"`
 	Redis RedisConfig `yaml:"redis" json:"redis" toml:"redis"`
+
+	// event flags
+	Event EventConfig `yaml:"event" json:"event" toml:"event"`
+
+	// jwt flags
+	JWT JWTConfig `yaml:"jwt" json:"jwt" toml:"jwt"`
+
+	// oidc flags
+	OIDC OIDCConfig `yaml:"oidc" json:"oidc" toml:"oidc"`
 }
 type CfgConfig struct {
 	Path string `yaml:"path" json:"path" toml:"path"`
+	// 远程配置
+	Provider     string            `yaml:"provider" json:"provider" toml:"provider"`
+	Endpoint     string            `yaml:"endpoint" json:"endpoint" toml:"endpoint"`
+	Format       string            `yaml:"format" json:"format" toml:"format"`
+	Headers      map[string]string `yaml:"headers" json:"headers" toml:"headers"`
+	CacheFile    string            `yaml:"cache_file" json:"cache_file" toml:"cache_file"`
+	PollInterval int               `yaml:"poll_interval" json:"poll_interval" toml:"poll_interval"`
 }
 type DBConfig struct {
 	InitDatabase              bool       `yaml:"init_database" json:"init_database" toml:"init_database"`
@@ -54,8 +70,12 @@ type LogConfig struct {
 	LogFileName      string `yaml:"log_file_name" json:"log_file_name" toml:"log_file_name"`
 	ErrorLogFileName string `yaml:"log_error_file_name" json:"log_error_file_name" toml:"log_error_file_name"`
 	LogFormat        string `yaml:"log_format" json:"log_format" toml:"log_format"`
-	LogReserveDays   int    `yaml:"log_reserve_days" json:"log_reserve_days" toml:"log_reserve_days"`
-	LogMaxSize       int    `yaml:"log_max_size" json:"log_max_size" toml:"log_max_size"`
+	LogReserveDays   int    `yaml:"log_reserve_days" json:"log_reserve_days" toml:"log_reserve_days"` // 轮转后的文件保留天数，0为永久保留
+	LogMaxSize       int    `yaml:"log_max_size" json:"log_max_size" toml:"log_max_size"`             // 单个文件最大MB，0为不按大小轮转
+	LogRotateDaily   bool   `yaml:"log_rotate_daily" json:"log_rotate_daily" toml:"log_rotate_daily"` // 每天轮转
+	LogCompress      bool   `yaml:"log_compress" json:"log_compress" toml:"log_compress"`             // gzip压缩轮转后的文件
+	// 为external时由外部logrotate轮转，收到SIGHUP时重新打开日志文件
+	LogRotate string `yaml:"log_rotate" json:"log_rotate" toml:"log_rotate"`
 }
 
 type ServerConfig struct {
@@ -72,3 +92,74 @@ type RedisConfig struct {
 	Password string `yaml:"redis_password" json:"redis_password" toml:"redis_password"`
 	DB       int    `yaml:"redis_db" json:"redis_db" toml:"redis_db"`
 }
+
+type EventConfig struct {
+	RedisConfig `yaml:",inline"`
+	// 异步分发
+	Async        bool   `yaml:"async" json:"async" toml:"async"`
+	Workers      int    `yaml:"workers" json:"workers" toml:"workers"`
+	QueueSize    int    `yaml:"queue_size" json:"queue_size" toml:"queue_size"`
+	Backpressure string `yaml:"backpressure" json:"backpressure" toml:"backpressure"`    // block、drop、error
+	CloseTimeout int    `yaml:"close_timeout" json:"close_timeout" toml:"close_timeout"` // 秒
+	// 跨进程传输
+	Transport    string `yaml:"transport" json:"transport" toml:"transport"` // redis_pubsub、redis_stream，为空时只在进程内分发
+	StreamPrefix string `yaml:"stream_prefix" json:"stream_prefix" toml:"stream_prefix"`
+	StreamMaxLen int64  `yaml:"stream_max_len" json:"stream_max_len" toml:"stream_max_len"` // 每个stream保留的大约消息数，默认100000
+	Consumer     string `yaml:"consumer" json:"consumer" toml:"consumer"`
+	// 发布没有订阅者的事件时记录指标
+	NoSubscriberMetric bool `yaml:"no_subscriber_metric" json:"no_subscriber_metric" toml:"no_subscriber_metric"`
+	// 死信存储
+	DeadLetter string `yaml:"dead_letter" json:"dead_letter" toml:"dead_letter"` // mysql、redis，为空时不保存
+	// 事务发件箱，需要先加载mysql组件
+	Outbox            bool `yaml:"outbox" json:"outbox" toml:"outbox"`
+	OutboxInterval    int  `yaml:"outbox_interval" json:"outbox_interval" toml:"outbox_interval"`             // 投递间隔，毫秒
+	OutboxBatchSize   int  `yaml:"outbox_batch_size" json:"outbox_batch_size" toml:"outbox_batch_size"`       // 每次投递的数量
+	OutboxRetention   int  `yaml:"outbox_retention" json:"outbox_retention" toml:"outbox_retention"`          // 已投递消息保留天数
+	OutboxMaxAttempts int  `yaml:"outbox_max_attempts" json:"outbox_max_attempts" toml:"outbox_max_attempts"` // 最大投递次数，默认10
+	// 事件日志，记录所有发布的事件用于重放
+	EventLog            string `yaml:"event_log" json:"event_log" toml:"event_log"` // mysql、file，为空时不记录
+	EventLogDir         string `yaml:"event_log_dir" json:"event_log_dir" toml:"event_log_dir"`
+	EventLogSegmentSize int64  `yaml:"event_log_segment_size" json:"event_log_segment_size" toml:"event_log_segment_size"` // 分段文件大小，MB
+	EventLogRetention   int    `yaml:"event_log_retention" json:"event_log_retention" toml:"event_log_retention"`          // 保留天数，0为永久保留
+}
+
+type JWTConfig struct {
+	Secret   string `yaml:"secret" json:"secret" toml:"secret"`
+	Issuer   string `yaml:"issuer" json:"issuer" toml:"issuer"`
+	Audience string `yaml:"audience" json:"audience" toml:"audience"`
+	Expire   int    `yaml:"expire" json:"expire" toml:"expire"` // 过期时间，秒，默认15分钟
+	Leeway   int    `yaml:"leeway" json:"leeway" toml:"leeway"` // 校验时间允许的误差，秒
+	// 签名算法，HS256、RS256、ES256、EdDSA，默认HS256，HS256使用secret签名
+	Algorithm string `yaml:"algorithm" json:"algorithm" toml:"algorithm"`
+	// 非对称密钥，第一个已生效的带私钥的密钥用于签名，其他密钥只用于校验。
+	// 轮换时将新密钥加到最前面并设置activate_at，旧密钥在新密钥生效后继续校验到token过期，
+	// 也可以通过expire_at指定旧密钥停止校验的时间
+	Keys []JWTKey `yaml:"keys" json:"keys" toml:"keys"`
+	// 校验时允许的签名算法，默认为签名算法和已配置密钥的算法
+	AllowedAlgorithms []string `yaml:"allowed_algorithms" json:"allowed_algorithms" toml:"allowed_algorithms"`
+	// 刷新token
+	RefreshExpire int    `yaml:"refresh_expire" json:"refresh_expire" toml:"refresh_expire"` // 过期时间，秒，默认7天
+	RefreshStore  string `yaml:"refresh_store" json:"refresh_store" toml:"refresh_store"`    // redis、mysql，为空时不支持刷新
+	KeyPrefix     string `yaml:"key_prefix" json:"key_prefix" toml:"key_prefix"`             // redis key前缀，默认jwt:
+	// 启用会话管理和token撤销，需要redis组件，校验token时会查询redis
+	Sessions bool `yaml:"sessions" json:"sessions" toml:"sessions"`
+}
+
+type JWTKey struct {
+	ID             string `yaml:"kid" json:"kid" toml:"kid"` // 为空时使用公钥摘要
+	PrivateKeyFile string `yaml:"private_key_file" json:"private_key_file" toml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file" json:"public_key_file" toml:"public_key_file"` // 只配置公钥时只用于校验
+	ActivateAt     string `yaml:"activate_at" json:"activate_at" toml:"activate_at"`             // 开始用于签名的时间，RFC3339格式，为空时立即生效
+	ExpireAt       string `yaml:"expire_at" json:"expire_at" toml:"expire_at"`                   // 停止校验的时间，RFC3339格式
+}
+
+type OIDCConfig struct {
+	Issuer       string   `yaml:"issuer" json:"issuer" toml:"issuer"` // 身份提供方地址，从issuer/.well-known/openid-configuration读取端点
+	ClientID     string   `yaml:"client_id" json:"client_id" toml:"client_id"`
+	ClientSecret string   `yaml:"client_secret" json:"client_secret" toml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url" json:"redirect_url" toml:"redirect_url"` // 回调地址，需要在身份提供方注册
+	Scopes       []string `yaml:"scopes" json:"scopes" toml:"scopes"`                   // 默认openid、profile、email
+	StateExpire  int      `yaml:"state_expire" json:"state_expire" toml:"state_expire"` // 登录的有效时间，秒，默认10分钟
+	Leeway       int      `yaml:"leeway" json:"leeway" toml:"leeway"`                   // 校验id token时间允许的误差，秒
+	KeyPrefix    string   `yaml:"key_prefix" json:"key_prefix" toml:"key_prefix"`       // redis key前缀，默认oidc:
+}
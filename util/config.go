@@ -1,21 +1,45 @@
 package util
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path"
+	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+	"github.com/mitchellh/mapstructure"
 	"github.com/sirupsen/logrus"
+	"github.com/subosito/gotenv"
+	"gopkg.in/ini.v1"
 	"gopkg.in/yaml.v3"
 )
 
-var loaders = map[string]func([]byte, interface{}) error{
-	".json": LoadFromJsonBytes,
-	".toml": LoadFromTomlBytes,
-	".yaml": LoadFromYamlBytes,
-	".yml":  LoadFromYamlBytes,
+// Loader 配置解析函数
+type Loader func([]byte, interface{}) error
+
+var (
+	loaders = map[string]Loader{
+		".json": LoadFromJsonBytes,
+		".toml": LoadFromTomlBytes,
+		".yaml": LoadFromYamlBytes,
+		".yml":  LoadFromYamlBytes,
+		".ini":  LoadFromIniBytes,
+		".hcl":  LoadFromHclBytes,
+		".env":  LoadFromEnvBytes,
+	}
+	loaderLock sync.RWMutex
+)
+
+// RegisterLoader 注册配置解析函数，ext为扩展名，如.conf
+func RegisterLoader(ext string, loader Loader) {
+	loaderLock.Lock()
+	defer loaderLock.Unlock()
+	loaders[normalizeExt(ext)] = loader
 }
 
 func LoadConfig(file string, v interface{}) error {
@@ -23,10 +47,80 @@ func LoadConfig(file string, v interface{}) error {
 	if nil != err {
 		return err
 	}
-	loader := loaders[strings.ToLower(path.Ext(file))]
+	return LoadConfigBytes(path.Ext(file), content, v)
+}
+
+// LoadConfigBytes 按格式解析配置内容，format为扩展名(.yaml)或格式名(yaml)，为空时根据内容识别格式
+func LoadConfigBytes(format string, content []byte, v interface{}) error {
+	format = normalizeExt(format)
+	if format == "" {
+		format = DetectFormat(content)
+	}
+	loaderLock.RLock()
+	loader, ok := loaders[format]
+	loaderLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("不支持的配置文件格式[%s]，可通过RegisterLoader注册", format)
+	}
 	return loader(content, v)
 }
 
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+var (
+	envLineRegexp  = regexp.MustCompile(`^(export\s+)?[A-Za-z_][A-Za-z0-9_.]*=`)
+	hclBlockRegexp = regexp.MustCompile(`^[\w-]+(\s+"[^"]*")*\s*\{$`)
+	iniLineRegexp  = regexp.MustCompile(`^(\[[^\]]+\]|[\w.-]+\s*=)`)
+)
+
+// DetectFormat 根据内容识别配置格式，返回扩展名
+func DetectFormat(content []byte) string {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return ".json"
+	}
+	var lines []string
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ".yaml"
+	}
+	isEnv, isIni := true, true
+	for _, line := range lines {
+		if hclBlockRegexp.MatchString(line) {
+			return ".hcl"
+		}
+		if !envLineRegexp.MatchString(line) {
+			isEnv = false
+		}
+		if !iniLineRegexp.MatchString(line) {
+			isIni = false
+		}
+	}
+	if isEnv {
+		return ".env"
+	}
+	if isIni {
+		var m map[string]interface{}
+		if toml.Unmarshal(trimmed, &m) == nil {
+			return ".toml"
+		}
+		return ".ini"
+	}
+	return ".yaml"
+}
+
 func LoadFromJsonBytes(b []byte, v interface{}) error {
 	return json.Unmarshal(b, v)
 }
@@ -39,6 +133,102 @@ func LoadFromTomlBytes(b []byte, v interface{}) error {
 	return toml.Unmarshal(b, v)
 }
 
+// LoadFromIniBytes 解析ini，默认分区的键在顶层，其余分区作为嵌套对象
+func LoadFromIniBytes(b []byte, v interface{}) error {
+	f, err := ini.Load(b)
+	if err != nil {
+		return err
+	}
+	m := map[string]interface{}{}
+	for _, section := range f.Sections() {
+		values := section.KeysHash()
+		if section.Name() == ini.DefaultSection {
+			for k, val := range values {
+				m[k] = val
+			}
+			continue
+		}
+		sm := map[string]interface{}{}
+		for k, val := range values {
+			sm[k] = val
+		}
+		m[section.Name()] = sm
+	}
+	return decodeMap(m, v)
+}
+
+// LoadFromHclBytes 解析hcl
+func LoadFromHclBytes(b []byte, v interface{}) error {
+	var m map[string]interface{}
+	if err := hcl.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	return decodeMap(flattenHcl(m).(map[string]interface{}), v)
+}
+
+// LoadFromEnvBytes 解析dotenv，键名转小写，"__"作为层级分隔符，如SERVER__LISTEN_PORT=8080
+func LoadFromEnvBytes(b []byte, v interface{}) error {
+	env, err := gotenv.StrictParse(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	m := map[string]interface{}{}
+	for k, val := range env {
+		keys := strings.Split(strings.ToLower(k), "__")
+		cur := m
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := cur[key].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				cur[key] = next
+			}
+			cur = next
+		}
+		cur[keys[len(keys)-1]] = val
+	}
+	return decodeMap(m, v)
+}
+
+// flattenHcl hcl将块解析为[]map，只有一个元素时展开为map
+func flattenHcl(v interface{}) interface{} {
+	switch val := v.(type) {
+	case []map[string]interface{}:
+		if len(val) == 1 {
+			return flattenHcl(val[0])
+		}
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = flattenHcl(item)
+		}
+		return list
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = flattenHcl(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = flattenHcl(item)
+		}
+		return val
+	}
+	return v
+}
+
+// decodeMap 将字符串值弱类型转换后按json标签写入结构体，嵌入的结构体字段展开到上一级
+func decodeMap(m map[string]interface{}, v interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          "json",
+		WeaklyTypedInput: true,
+		Squash:           true,
+		Result:           v,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(m)
+}
+
 func GenerageSampleYaml(v interface{}) {
 	yb, _ := yaml.Marshal(v)
 	println(string(yb))
@@ -46,7 +236,7 @@ func GenerageSampleYaml(v interface{}) {
 
 func MustLoadConfig(file string, v interface{}) {
 	if err := LoadConfig(file, v); err != nil {
-		logrus.Errorf("加载配置文件[%s]失败", file)
+		logrus.Errorf("加载配置文件[%s]失败:%s", file, err.Error())
 		return
 	}
 	logrus.Infof("配置文件[%s]加载成功", file)
@@ -0,0 +1,136 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 与types.Config一致，同时声明yaml、json和toml标签，ini、hcl和dotenv按json标签映射
+type testServer struct {
+	ListenPort int    `yaml:"listen_port" json:"listen_port" toml:"listen_port"`
+	Host       string `yaml:"host" json:"host" toml:"host"`
+}
+
+type testConfig struct {
+	Name   string     `yaml:"name" json:"name" toml:"name"`
+	Debug  bool       `yaml:"debug" json:"debug" toml:"debug"`
+	Server testServer `yaml:"server" json:"server" toml:"server"`
+}
+
+func TestLoadConfigBytes(t *testing.T) {
+	want := testConfig{Name: "app", Debug: true, Server: testServer{ListenPort: 8080, Host: "127.0.0.1"}}
+	cases := []struct {
+		format  string
+		content string
+	}{
+		{"json", `{"name":"app","debug":true,"server":{"listen_port":8080,"host":"127.0.0.1"}}`},
+		{"yaml", "name: app\ndebug: true\nserver:\n  listen_port: 8080\n  host: 127.0.0.1\n"},
+		{".yml", "name: app\ndebug: true\nserver:\n  listen_port: 8080\n  host: 127.0.0.1\n"},
+		{"toml", "name = \"app\"\ndebug = true\n[server]\nlisten_port = 8080\nhost = \"127.0.0.1\"\n"},
+		{"ini", "name = app\ndebug = true\n[server]\nlisten_port = 8080\nhost = 127.0.0.1\n"},
+		{"hcl", "name = \"app\"\ndebug = true\nserver {\n  listen_port = 8080\n  host = \"127.0.0.1\"\n}\n"},
+		{"env", "NAME=app\nDEBUG=true\nSERVER__LISTEN_PORT=8080\nexport SERVER__HOST=127.0.0.1\n"},
+		{"", "NAME=app\nDEBUG=true\nSERVER__LISTEN_PORT=8080\nSERVER__HOST=127.0.0.1\n"},
+	}
+	for _, c := range cases {
+		var got testConfig
+		if err := LoadConfigBytes(c.format, []byte(c.content), &got); err != nil {
+			t.Errorf("格式[%s]解析失败:%s", c.format, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("格式[%s]解析结果不正确:%+v", c.format, got)
+		}
+	}
+}
+
+// 与types.EventConfig一致，嵌入的结构体字段与其他字段在同一级
+type testRedis struct {
+	Addr string `yaml:"redis_addr" json:"redis_addr" toml:"redis_addr"`
+	DB   int    `yaml:"redis_db" json:"redis_db" toml:"redis_db"`
+}
+
+type testEvent struct {
+	testRedis `yaml:",inline"`
+	Async     bool `yaml:"async" json:"async" toml:"async"`
+}
+
+type testEmbedConfig struct {
+	Event testEvent `yaml:"event" json:"event" toml:"event"`
+}
+
+func TestLoadConfigBytesEmbedded(t *testing.T) {
+	want := testEvent{testRedis: testRedis{Addr: "127.0.0.1:6379", DB: 2}, Async: true}
+	cases := []struct {
+		format  string
+		content string
+	}{
+		{"yaml", "event:\n  redis_addr: 127.0.0.1:6379\n  redis_db: 2\n  async: true\n"},
+		{"ini", "[event]\nredis_addr = 127.0.0.1:6379\nredis_db = 2\nasync = true\n"},
+		{"hcl", "event {\n  redis_addr = \"127.0.0.1:6379\"\n  redis_db = 2\n  async = true\n}\n"},
+		{"env", "EVENT__REDIS_ADDR=127.0.0.1:6379\nEVENT__REDIS_DB=2\nEVENT__ASYNC=true\n"},
+	}
+	for _, c := range cases {
+		var got testEmbedConfig
+		if err := LoadConfigBytes(c.format, []byte(c.content), &got); err != nil {
+			t.Errorf("格式[%s]解析失败:%s", c.format, err)
+			continue
+		}
+		if got.Event != want {
+			t.Errorf("格式[%s]嵌入的结构体解析不正确:%+v", c.format, got.Event)
+		}
+	}
+}
+
+func TestLoadConfigBytesUnknownFormat(t *testing.T) {
+	var got testConfig
+	err := LoadConfigBytes("xml", []byte("<name>app</name>"), &got)
+	if err == nil || !strings.Contains(err.Error(), ".xml") {
+		t.Errorf("不支持的格式应返回错误:%v", err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := []struct {
+		content string
+		want    string
+	}{
+		{`{"name":"app"}`, ".json"},
+		{"name: app\nserver:\n  listen_port: 8080\n", ".yaml"},
+		{"# comment\nNAME=app\nexport PORT=8080\n", ".env"},
+		{"name = \"app\"\n[server]\nlisten_port = 8080\n", ".toml"},
+		{"; comment\nname = app\n[server]\nhost = 127.0.0.1\n", ".ini"},
+		{"server {\n  listen_port = 8080\n}\n", ".hcl"},
+		{"", ".yaml"},
+	}
+	for _, c := range cases {
+		if got := DetectFormat([]byte(c.content)); got != c.want {
+			t.Errorf("%q:期望%s，实际%s", c.content, c.want, got)
+		}
+	}
+}
+
+func TestRegisterLoader(t *testing.T) {
+	RegisterLoader("CONF", func(b []byte, v interface{}) error {
+		return LoadFromEnvBytes(b, v)
+	})
+	defer func() {
+		loaderLock.Lock()
+		delete(loaders, ".conf")
+		loaderLock.Unlock()
+	}()
+
+	file := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(file, []byte("NAME=app\nSERVER__LISTEN_PORT=9090\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var got testConfig
+	if err := LoadConfig(file, &got); err != nil {
+		t.Fatalf("自定义格式解析失败:%s", err)
+	}
+	if got.Name != "app" || got.Server.ListenPort != 9090 {
+		t.Errorf("自定义格式解析结果不正确:%+v", got)
+	}
+}